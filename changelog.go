@@ -0,0 +1,344 @@
+package tagpr
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// the heading of the changelog section generated by gh2changelog
+// e.g. "## [v1.3.0](https://github.com/Songmu/tagpr/compare/v1.2.0...v1.3.0) - 2024-06-01"
+var changelogHeadingReg = regexp.MustCompile(`\A## \[([^\]]*)\]\(([^)]*)\)(?: - (\S+))?`)
+
+type changelogHeadingArg struct {
+	Tag, Link, Date, Anchor string
+}
+
+// renderChangelogHeading rewrites the heading of the changelog section with the template.
+// The fields are .Tag, .Link (the comparison link), .Date and .Anchor.
+func renderChangelogHeading(section, tmplStr string) (string, error) {
+	tmpl, err := template.New("changelog link template").Parse(tmplStr)
+	if err != nil {
+		return "", err
+	}
+	m := changelogHeadingReg.FindStringSubmatch(section)
+	if m == nil {
+		return section, nil
+	}
+	arg := &changelogHeadingArg{Tag: m[1], Link: m[2], Date: m[3]}
+	text := arg.Tag
+	if arg.Date != "" {
+		text += " - " + arg.Date
+	}
+	arg.Anchor = headingAnchor(text)
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, arg); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(b.String()) + section[len(m[0]):], nil
+}
+
+var anchorIgnoreReg = regexp.MustCompile(`[^\p{L}\p{N}\- _]`)
+
+// headingAnchor returns the anchor of the heading text as GitHub generates.
+// e.g. "v1.3.0 - 2024-06-01" => "v130---2024-06-01"
+func headingAnchor(text string) string {
+	text = anchorIgnoreReg.ReplaceAllString(strings.ToLower(text), "")
+	return strings.ReplaceAll(text, " ", "-")
+}
+
+// the pull request line of the release notes, e.g. "* Add feature by @Songmu in https://github.com/Songmu/tagpr/pull/1"
+var notesPullLineReg = regexp.MustCompile(`^([*-] )(.*/pull/(\d+))$`)
+
+// whatsChangedLines reports whether each line is in the "What's Changed" section of the notes,
+// that is, below the heading of it until the next heading of the same or upper level, to leave
+// the lines of the other sections like "New Contributors" alone, whose lines also end with the
+// URL of the pull request. All the lines are in it if the notes have no such heading.
+func whatsChangedLines(lines []string) []bool {
+	in := make([]bool, len(lines))
+	found, level := false, 0
+	for i, line := range lines {
+		if m := mdHeadingReg.FindStringSubmatch(line); m != nil {
+			if strings.Contains(m[2], "What's Changed") {
+				found, level = true, len(m[1])
+			} else if len(m[1]) <= level {
+				level = 0
+			}
+			continue
+		}
+		in[i] = level > 0
+	}
+	if !found {
+		for i := range in {
+			in[i] = true
+		}
+	}
+	return in
+}
+
+// applyLabelPrefixes prefixes the pull request lines of the notes by the first matched label
+// in the order of the prefixes. The labels of the pull request are retrieved by labelsOf.
+func applyLabelPrefixes(notes string, lps []labelPrefix, labelsOf func(int) ([]string, error)) (string, error) {
+	lines := strings.Split(notes, "\n")
+	changed := whatsChangedLines(lines)
+	for i, line := range lines {
+		m := notesPullLineReg.FindStringSubmatch(line)
+		if m == nil || !changed[i] {
+			continue
+		}
+		num, _ := strconv.Atoi(m[3])
+		labels, err := labelsOf(num)
+		if err != nil {
+			return "", err
+		}
+	L:
+		for _, lp := range lps {
+			for _, l := range labels {
+				if labelMatches(l, lp.label) {
+					lines[i] = m[1] + lp.prefix + " " + m[2]
+					break L
+				}
+			}
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+const (
+	notesSortMergedAt = "mergedAt"
+	notesSortNumber   = "number"
+	notesSortTitle    = "title"
+)
+
+// sortNotes sorts the pull request lines in each list of the notes by the merge time,
+// the pull request number or the title. The other items in the list, e.g. the direct
+// commits, are put after them in the original order. The merge times are retrieved by
+// mergedAtOf only for notesSortMergedAt.
+func sortNotes(notes, by string, mergedAtOf func(int) (time.Time, error)) (string, error) {
+	type item struct {
+		line, title string
+		num         int
+		mergedAt    time.Time
+	}
+	lines := strings.Split(notes, "\n")
+	changed := whatsChangedLines(lines)
+	for i := 0; i < len(lines); {
+		if !changed[i] || !strings.HasPrefix(lines[i], "* ") && !strings.HasPrefix(lines[i], "- ") {
+			i++
+			continue
+		}
+		j := i
+		var items []item
+		for ; j < len(lines) && (strings.HasPrefix(lines[j], "* ") || strings.HasPrefix(lines[j], "- ")); j++ {
+			it := item{line: lines[j]}
+			if m := notesPullLineReg.FindStringSubmatch(lines[j]); m != nil {
+				it.num, _ = strconv.Atoi(m[3])
+				it.title = strings.ToLower(m[2])
+				if by == notesSortMergedAt {
+					t, err := mergedAtOf(it.num)
+					if err != nil {
+						return "", err
+					}
+					it.mergedAt = t
+				}
+			}
+			items = append(items, it)
+		}
+		sort.SliceStable(items, func(a, b int) bool {
+			x, y := items[a], items[b]
+			if x.num == 0 || y.num == 0 {
+				return x.num != 0 && y.num == 0
+			}
+			switch by {
+			case notesSortMergedAt:
+				if !x.mergedAt.Equal(y.mergedAt) {
+					return x.mergedAt.Before(y.mergedAt)
+				}
+			case notesSortTitle:
+				if x.title != y.title {
+					return x.title < y.title
+				}
+			}
+			return x.num < y.num
+		})
+		for k, it := range items {
+			lines[i+k] = it.line
+		}
+		i = j
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// breakingChanges returns the pull request lines of the notes labeled by any of the labels
+func breakingChanges(notes string, labels []string, labelsOf func(int) ([]string, error)) ([]string, error) {
+	if len(labels) == 0 {
+		return nil, nil
+	}
+	var items []string
+	lines := strings.Split(notes, "\n")
+	changed := whatsChangedLines(lines)
+	for i, line := range lines {
+		m := notesPullLineReg.FindStringSubmatch(line)
+		if m == nil || !changed[i] {
+			continue
+		}
+		num, _ := strconv.Atoi(m[3])
+		ls, err := labelsOf(num)
+		if err != nil {
+			return nil, err
+		}
+		if hasAnyLabel(ls, labels) {
+			items = append(items, m[2])
+		}
+	}
+	return items, nil
+}
+
+// reactionCounts returns the numbers of the :+1: reactions of the pull requests in the notes
+// keyed by the pull request number.
+func reactionCounts(notes string, reactionsOf func(int) (int, error)) (map[int]int, error) {
+	counts := map[int]int{}
+	lines := strings.Split(notes, "\n")
+	changed := whatsChangedLines(lines)
+	for i, line := range lines {
+		m := notesPullLineReg.FindStringSubmatch(line)
+		if m == nil || !changed[i] {
+			continue
+		}
+		num, _ := strconv.Atoi(m[3])
+		if _, ok := counts[num]; ok {
+			continue
+		}
+		n, err := reactionsOf(num)
+		if err != nil {
+			return nil, err
+		}
+		counts[num] = n
+	}
+	return counts, nil
+}
+
+// highlights returns the pull request lines of the notes with the :+1: reactions
+// of the threshold or more.
+func highlights(notes string, counts map[int]int, threshold int) []string {
+	if threshold <= 0 {
+		return nil
+	}
+	var items []string
+	lines := strings.Split(notes, "\n")
+	changed := whatsChangedLines(lines)
+	for i, line := range lines {
+		m := notesPullLineReg.FindStringSubmatch(line)
+		if m == nil || !changed[i] {
+			continue
+		}
+		num, _ := strconv.Atoi(m[3])
+		if counts[num] >= threshold {
+			items = append(items, m[2])
+		}
+	}
+	return items
+}
+
+// closingIssueReg matches the references of the issues closed by the pull request with the
+// closing keywords of GitHub, e.g. "Fixes #12", "closes owner/repo#34" or "Resolves https://github.com/owner/repo/issues/56".
+var closingIssueReg = regexp.MustCompile(
+	`(?i)(?:^|[^\w/])(?:close[sd]?|fix(?:e[sd])?|resolve[sd]?):?\s+` +
+		`(?:([\w.-]+/[\w.-]+)?#(\d+)|https?://[^/\s]+/([\w.-]+/[\w.-]+)/issues/(\d+))`)
+
+// closedIssueLinks returns the markdown links of the issues closed by the pull request body.
+// The issues in the repository are shown as "#12", and the ones in the others as "owner/repo#12".
+func closedIssueLinks(body, host, repo string) []string {
+	var links []string
+	seen := map[string]bool{}
+	for _, m := range closingIssueReg.FindAllStringSubmatch(body, -1) {
+		r, num := m[1], m[2]
+		if num == "" {
+			r, num = m[3], m[4]
+		}
+		if r == "" {
+			r = repo
+		}
+		text := "#" + num
+		if !strings.EqualFold(r, repo) {
+			text = r + text
+		}
+		if seen[strings.ToLower(text)] {
+			continue
+		}
+		seen[strings.ToLower(text)] = true
+		links = append(links, fmt.Sprintf("[%s](https://%s/%s/issues/%s)", text, host, r, num))
+	}
+	return links
+}
+
+// appendClosedIssues appends the links of the issues closed by each pull request to the lines
+// of the notes. It must be applied after the other processing of the pull request lines, as the
+// lines no longer end with the URL of the pull request.
+func appendClosedIssues(notes string, issuesOf func(int) ([]string, error)) (string, error) {
+	lines := strings.Split(notes, "\n")
+	changed := whatsChangedLines(lines)
+	for i, line := range lines {
+		m := notesPullLineReg.FindStringSubmatch(line)
+		if m == nil || !changed[i] {
+			continue
+		}
+		num, _ := strconv.Atoi(m[3])
+		links, err := issuesOf(num)
+		if err != nil {
+			return "", err
+		}
+		if len(links) > 0 {
+			lines[i] = line + " (closes " + strings.Join(links, ", ") + ")"
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func hasAnyLabel(labels, targets []string) bool {
+	for _, l := range labels {
+		for _, t := range targets {
+			if labelMatches(l, t) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// insertBreakingChanges puts the "Breaking Changes" section with the emphasized items at the top
+// of the notes, that is, below the heading if the notes are the section of the changelog.
+func insertBreakingChanges(notes string, items []string, bullet string) string {
+	if len(items) == 0 {
+		return notes
+	}
+	section := "### :warning: Breaking Changes\n"
+	for _, item := range items {
+		section += bullet + "**" + item + "**\n"
+	}
+	return insertSection(notes, section)
+}
+
+// insertHighlights puts the "Highlights" section at the top of the notes as well as insertBreakingChanges.
+func insertHighlights(notes string, items []string, bullet string) string {
+	if len(items) == 0 {
+		return notes
+	}
+	section := "### :star: Highlights\n"
+	for _, item := range items {
+		section += bullet + item + "\n"
+	}
+	return insertSection(notes, section)
+}
+
+func insertSection(notes, section string) string {
+	if m := changelogHeadingReg.FindStringIndex(notes); m != nil {
+		return notes[:m[1]] + "\n\n" + section + "\n" + strings.TrimLeft(notes[m[1]:], "\n")
+	}
+	return section + "\n" + notes
+}
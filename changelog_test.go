@@ -0,0 +1,279 @@
+package tagpr
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHeadingAnchor(t *testing.T) {
+	testCases := []struct {
+		text, expect string
+	}{
+		{"v1.3.0 - 2024-06-01", "v130---2024-06-01"},
+		{"backend/v1.3.0", "backendv130"},
+		{"app@1.3.0", "app130"},
+	}
+	for _, tc := range testCases {
+		if got := headingAnchor(tc.text); got != tc.expect {
+			t.Errorf("got: %s, expected: %s", got, tc.expect)
+		}
+	}
+}
+
+func TestRenderChangelogHeading(t *testing.T) {
+	const section = "## [v1.3.0](https://github.com/Songmu/tagpr/compare/v1.2.0...v1.3.0) - 2024-06-01\n- feature\n"
+	testCases := []struct {
+		name, tmpl, expect string
+	}{{
+		name:   "heading with anchor",
+		tmpl:   `<a id="{{.Anchor}}"></a>` + "\n" + `## {{.Tag}} ({{.Date}}) [compare]({{.Link}})`,
+		expect: "<a id=\"v130---2024-06-01\"></a>\n## v1.3.0 (2024-06-01) [compare](https://github.com/Songmu/tagpr/compare/v1.2.0...v1.3.0)\n- feature\n",
+	}, {
+		name:   "same as default",
+		tmpl:   `## [{{.Tag}}]({{.Link}}) - {{.Date}}`,
+		expect: section,
+	}}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := renderChangelogHeading(section, tc.tmpl)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.expect {
+				t.Errorf("got:\n%s\nexpected:\n%s", got, tc.expect)
+			}
+		})
+	}
+}
+
+func TestApplyLabelPrefixes(t *testing.T) {
+	const notes = `## What's Changed
+* Fix crash by @Songmu in https://github.com/Songmu/tagpr/pull/1
+* Add feature by @Songmu in https://github.com/Songmu/tagpr/pull/2
+* Update docs by @Songmu in https://github.com/Songmu/tagpr/pull/3
+
+**Full Changelog**: https://github.com/Songmu/tagpr/compare/v1.0.0...v1.0.1`
+	labels := map[int][]string{
+		1: {"bug"},
+		2: {"documentation", "enhancement", "bug"},
+		3: {"documentation"},
+	}
+	lps := []labelPrefix{{"enhancement", "Feature:"}, {"bug", "Fix:"}}
+	got, err := applyLabelPrefixes(notes, lps, func(num int) ([]string, error) {
+		l, ok := labels[num]
+		if !ok {
+			return nil, fmt.Errorf("unexpected pull request: %d", num)
+		}
+		return l, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := `## What's Changed
+* Fix: Fix crash by @Songmu in https://github.com/Songmu/tagpr/pull/1
+* Feature: Add feature by @Songmu in https://github.com/Songmu/tagpr/pull/2
+* Update docs by @Songmu in https://github.com/Songmu/tagpr/pull/3
+
+**Full Changelog**: https://github.com/Songmu/tagpr/compare/v1.0.0...v1.0.1`
+	if got != expect {
+		t.Errorf("got:\n%s\nexpected:\n%s", got, expect)
+	}
+}
+
+func TestBreakingChanges(t *testing.T) {
+	labels := map[int][]string{1: {"breaking"}, 2: {"enhancement"}}
+	labelsOf := func(num int) ([]string, error) {
+		return labels[num], nil
+	}
+	const notes = "## What's Changed\n" +
+		"* Drop Go 1.18 by @Songmu in https://github.com/Songmu/tagpr/pull/1\n" +
+		"* Add feature by @Songmu in https://github.com/Songmu/tagpr/pull/2\n"
+	items, err := breakingChanges(notes, []string{"breaking"}, labelsOf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := "### :warning: Breaking Changes\n" +
+		"* **Drop Go 1.18 by @Songmu in https://github.com/Songmu/tagpr/pull/1**\n\n" + notes
+	if got := insertBreakingChanges(notes, items, "* "); got != expect {
+		t.Errorf("got:\n%s\nexpected:\n%s", got, expect)
+	}
+
+	const section = "## [v2.0.0](https://github.com/Songmu/tagpr/compare/v1.2.0...v2.0.0) - 2024-06-01\n" +
+		"- Drop Go 1.18 by @Songmu in https://github.com/Songmu/tagpr/pull/1\n"
+	expect = "## [v2.0.0](https://github.com/Songmu/tagpr/compare/v1.2.0...v2.0.0) - 2024-06-01\n\n" +
+		"### :warning: Breaking Changes\n" +
+		"- **Drop Go 1.18 by @Songmu in https://github.com/Songmu/tagpr/pull/1**\n\n" +
+		"- Drop Go 1.18 by @Songmu in https://github.com/Songmu/tagpr/pull/1\n"
+	if got := insertBreakingChanges(section, items, "- "); got != expect {
+		t.Errorf("got:\n%s\nexpected:\n%s", got, expect)
+	}
+	if got := insertBreakingChanges(notes, nil, "* "); got != notes {
+		t.Errorf("the notes should not be changed without breaking changes, but got:\n%s", got)
+	}
+
+	// disabled without tagpr.breakingLabels
+	if items, err := breakingChanges(notes, nil, labelsOf); err != nil || items != nil {
+		t.Errorf("no breaking changes should be found without the labels, but got: %v, %v", items, err)
+	}
+}
+
+func TestHighlights(t *testing.T) {
+	const notes = "## What's Changed\n" +
+		"* Add feature by @Songmu in https://github.com/Songmu/tagpr/pull/1\n" +
+		"* Fix typo by @Songmu in https://github.com/Songmu/tagpr/pull/2\n" +
+		"* Revert feature by @Songmu in https://github.com/Songmu/tagpr/pull/3\n"
+	calls := 0
+	counts, err := reactionCounts(notes, func(num int) (int, error) {
+		calls++
+		return map[int]int{1: 7, 2: 1, 3: 5}[num], nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 || counts[1] != 7 || counts[3] != 5 {
+		t.Errorf("unexpected counts: %v (calls: %d)", counts, calls)
+	}
+	items := highlights(notes, counts, 5)
+	expect := "### :star: Highlights\n" +
+		"* Add feature by @Songmu in https://github.com/Songmu/tagpr/pull/1\n" +
+		"* Revert feature by @Songmu in https://github.com/Songmu/tagpr/pull/3\n\n" + notes
+	if got := insertHighlights(notes, items, "* "); got != expect {
+		t.Errorf("got:\n%s\nexpected:\n%s", got, expect)
+	}
+	if items := highlights(notes, counts, 0); items != nil {
+		t.Errorf("highlights should be disabled by zero, but got: %v", items)
+	}
+}
+
+func TestClosedIssueLinks(t *testing.T) {
+	testCases := []struct {
+		name   string
+		body   string
+		expect []string
+	}{
+		{"none", "Refactor the parser, see #12", nil},
+		{"keywords", "Fixes #12\nThis also closes: #34 and resolved #12", []string{
+			"[#12](https://github.com/Songmu/tagpr/issues/12)",
+			"[#34](https://github.com/Songmu/tagpr/issues/34)",
+		}},
+		{"other repo", "Close Songmu/gh2changelog#5", []string{
+			"[Songmu/gh2changelog#5](https://github.com/Songmu/gh2changelog/issues/5)",
+		}},
+		{"url", "fix https://github.com/Songmu/tagpr/issues/56", []string{
+			"[#56](https://github.com/Songmu/tagpr/issues/56)",
+		}},
+		{"not a keyword", "prefix #12 and suffixes #13", nil},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := closedIssueLinks(tc.body, "github.com", "Songmu/tagpr")
+			if !reflect.DeepEqual(got, tc.expect) {
+				t.Errorf("got: %v, expected: %v", got, tc.expect)
+			}
+		})
+	}
+}
+
+func TestAppendClosedIssues(t *testing.T) {
+	const notes = "## What's Changed\n" +
+		"* Add feature by @Songmu in https://github.com/Songmu/tagpr/pull/1\n" +
+		"* Fix typo by @Songmu in https://github.com/Songmu/tagpr/pull/2\n" +
+		"\n" +
+		"## New Contributors\n" +
+		"* @Songmu made their first contribution in https://github.com/Songmu/tagpr/pull/1\n"
+	got, err := appendClosedIssues(notes, func(num int) ([]string, error) {
+		if num == 1 {
+			return []string{"[#3](https://github.com/Songmu/tagpr/issues/3)", "[#4](https://github.com/Songmu/tagpr/issues/4)"}, nil
+		}
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := "## What's Changed\n" +
+		"* Add feature by @Songmu in https://github.com/Songmu/tagpr/pull/1 (closes [#3](https://github.com/Songmu/tagpr/issues/3), [#4](https://github.com/Songmu/tagpr/issues/4))\n" +
+		"* Fix typo by @Songmu in https://github.com/Songmu/tagpr/pull/2\n" +
+		"\n" +
+		"## New Contributors\n" +
+		"* @Songmu made their first contribution in https://github.com/Songmu/tagpr/pull/1\n"
+	if got != expect {
+		t.Errorf("got:\n%s\nexpected:\n%s", got, expect)
+	}
+}
+
+func TestWhatsChangedLines(t *testing.T) {
+	testCases := []struct {
+		name   string
+		lines  []string
+		expect []bool
+	}{{
+		name:   "release notes",
+		lines:  []string{"## What's Changed", "* a", "## New Contributors", "* b"},
+		expect: []bool{false, true, false, false},
+	}, {
+		name:   "categories of release.yml",
+		lines:  []string{"## What's Changed", "### Features", "* a", "### Others", "* b", "## New Contributors", "* c"},
+		expect: []bool{false, false, true, false, true, false, false},
+	}, {
+		name:   "changelog section",
+		lines:  []string{"## [v1.2.0](https://example.com) - 2024-06-01", "### Highlights", "* a", "### What's Changed", "* b", "### New Contributors", "* c"},
+		expect: []bool{false, false, false, false, true, false, false},
+	}, {
+		name:   "no heading",
+		lines:  []string{"* a", "* b"},
+		expect: []bool{true, true},
+	}}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := whatsChangedLines(tc.lines); !reflect.DeepEqual(got, tc.expect) {
+				t.Errorf("got: %v, expected: %v", got, tc.expect)
+			}
+		})
+	}
+}
+
+func TestSortNotes(t *testing.T) {
+	const notes = "## What's Changed\n" +
+		"* fix typo by @Songmu in https://github.com/Songmu/tagpr/pull/3\n" +
+		"* Add feature by @Songmu in https://github.com/Songmu/tagpr/pull/10\n" +
+		"* Bump deps by @Songmu in https://github.com/Songmu/tagpr/pull/2\n" +
+		"### Direct Commits\n" +
+		"* Update README (abc1234) by Songmu\n" +
+		"\n" +
+		"**Full Changelog**: https://github.com/Songmu/tagpr/compare/v1.1.0...v1.2.0"
+	base := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	mergedAt := map[int]time.Time{2: base.Add(2 * time.Hour), 3: base, 10: base.Add(time.Hour)}
+	mergedAtOf := func(num int) (time.Time, error) {
+		return mergedAt[num], nil
+	}
+	testCases := []struct {
+		by     string
+		expect []int
+	}{
+		{notesSortMergedAt, []int{3, 10, 2}},
+		{notesSortNumber, []int{2, 3, 10}},
+		{notesSortTitle, []int{10, 2, 3}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.by, func(t *testing.T) {
+			got, err := sortNotes(notes, tc.by, mergedAtOf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			lines := strings.Split(got, "\n")
+			for i, num := range tc.expect {
+				if !strings.HasSuffix(lines[i+1], fmt.Sprintf("/pull/%d", num)) {
+					t.Errorf("line %d should be #%d:\n%s", i+1, num, got)
+				}
+			}
+			if !strings.HasSuffix(got, "### Direct Commits\n"+
+				"* Update README (abc1234) by Songmu\n\n"+
+				"**Full Changelog**: https://github.com/Songmu/tagpr/compare/v1.1.0...v1.2.0") {
+				t.Errorf("the rest should be kept:\n%s", got)
+			}
+		})
+	}
+}
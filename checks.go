@@ -0,0 +1,128 @@
+package tagpr
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/go-github/v47/github"
+)
+
+const (
+	defaultChecksTimeout = 10 * time.Minute
+	checksPollInterval   = 15 * time.Second
+)
+
+// waitForChecks waits for the required status checks of the branch protection to pass
+// on the commit. It returns an error if any of them fail or the timeout exceeds.
+func (tp *tagpr) waitForChecks(ctx context.Context, branch, sha string, timeout time.Duration) error {
+	rsc, resp, err := tp.gh.Repositories.GetRequiredStatusChecks(ctx, tp.owner, tp.repo, branch)
+	if err != nil {
+		// GitHub responds 404 if the branch is not protected or has no required status checks
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			log.Printf("no required status checks are found for %q, so skip waiting for them\n", branch)
+			return nil
+		}
+		return fmt.Errorf("failed to get the required status checks of %q. The token needs "+
+			"the permission to read the branch protection for %s: %w", branch, configWaitForChecks, err)
+	}
+	required := rsc.Contexts
+	for _, c := range rsc.Checks {
+		required = append(required, c.Context)
+	}
+	if len(required) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	for {
+		runs, statuses, err := tp.listChecks(ctx, sha)
+		if err != nil {
+			return err
+		}
+		pending, failed := evalChecks(required, runs, statuses)
+		if len(failed) > 0 {
+			return fmt.Errorf("the required checks failed on %s, so refuse to tag: %v", sha, failed)
+		}
+		if len(pending) == 0 {
+			return nil
+		}
+		log.Printf("waiting for the required checks: %v\n", pending)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out after %s waiting for the required checks: %v", timeout, pending)
+		case <-time.After(checksPollInterval):
+		}
+	}
+}
+
+// listChecks returns all the check runs and the commit statuses of the commit through the pages
+func (tp *tagpr) listChecks(ctx context.Context, sha string) ([]*github.CheckRun, []*github.RepoStatus, error) {
+	var runs []*github.CheckRun
+	opt := &github.ListCheckRunsOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		res, resp, err := tp.gh.Checks.ListCheckRunsForRef(ctx, tp.owner, tp.repo, sha, opt)
+		if err != nil {
+			return nil, nil, err
+		}
+		runs = append(runs, res.CheckRuns...)
+		if resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+	var statuses []*github.RepoStatus
+	lopt := &github.ListOptions{PerPage: 100}
+	for {
+		status, resp, err := tp.gh.Repositories.GetCombinedStatus(ctx, tp.owner, tp.repo, sha, lopt)
+		if err != nil {
+			return nil, nil, err
+		}
+		statuses = append(statuses, status.Statuses...)
+		if resp.NextPage == 0 {
+			break
+		}
+		lopt.Page = resp.NextPage
+	}
+	return runs, statuses, nil
+}
+
+// evalChecks returns the names of the required checks that are pending and failed
+// from the check runs and the commit statuses.
+func evalChecks(required []string, runs []*github.CheckRun, statuses []*github.RepoStatus) (pending, failed []string) {
+	for _, name := range required {
+		state := "pending"
+		for _, r := range runs {
+			if r.GetName() != name || r.GetStatus() != "completed" {
+				continue
+			}
+			switch r.GetConclusion() {
+			case "success", "neutral", "skipped":
+				state = "success"
+			default:
+				state = "failure"
+			}
+		}
+		for _, s := range statuses {
+			if s.GetContext() != name {
+				continue
+			}
+			switch s.GetState() {
+			case "success":
+				state = "success"
+			case "failure", "error":
+				state = "failure"
+			}
+		}
+		switch state {
+		case "pending":
+			pending = append(pending, name)
+		case "failure":
+			failed = append(failed, name)
+		}
+	}
+	return pending, failed
+}
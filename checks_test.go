@@ -0,0 +1,73 @@
+package tagpr
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v47/github"
+)
+
+func TestEvalChecks(t *testing.T) {
+	run := func(name, status, conclusion string) *github.CheckRun {
+		return &github.CheckRun{Name: &name, Status: &status, Conclusion: &conclusion}
+	}
+	st := func(context, state string) *github.RepoStatus {
+		return &github.RepoStatus{Context: &context, State: &state}
+	}
+	runs := []*github.CheckRun{
+		run("test", "completed", "success"),
+		run("lint", "in_progress", ""),
+		run("build", "completed", "failure"),
+		run("docs", "completed", "skipped"),
+	}
+	statuses := []*github.RepoStatus{
+		st("ci/legacy", "success"),
+		st("ci/deploy", "pending"),
+		st("ci/broken", "error"),
+	}
+	required := []string{"test", "lint", "build", "docs", "ci/legacy", "ci/deploy", "ci/broken", "missing"}
+	pending, failed := evalChecks(required, runs, statuses)
+	if expect := []string{"lint", "ci/deploy", "missing"}; !reflect.DeepEqual(pending, expect) {
+		t.Errorf("pending: %v, expected: %v", pending, expect)
+	}
+	if expect := []string{"build", "ci/broken"}; !reflect.DeepEqual(failed, expect) {
+		t.Errorf("failed: %v, expected: %v", failed, expect)
+	}
+}
+
+func TestWaitForChecks(t *testing.T) {
+	fake := newFakeGitHub(t, nil)
+	tp := &tagpr{gh: fake.client(), owner: testOwner, repo: testRepo}
+	ctx := context.Background()
+
+	// the branch without the required status checks
+	if err := tp.waitForChecks(ctx, "main", "abc", time.Minute); err != nil {
+		t.Errorf("error should be nil for the unprotected branch, but got: %s", err)
+	}
+
+	// the required checks on the second pages
+	fake.requiredChecks = []string{"test", "ci/legacy"}
+	for i := 0; i < 120; i++ {
+		name, status, conclusion := fmt.Sprintf("job%d", i), "completed", "success"
+		fake.checkRuns = append(fake.checkRuns, &github.CheckRun{Name: &name, Status: &status, Conclusion: &conclusion})
+	}
+	name, status, conclusion := "test", "completed", "success"
+	fake.checkRuns = append(fake.checkRuns, &github.CheckRun{Name: &name, Status: &status, Conclusion: &conclusion})
+	for i := 0; i < 100; i++ {
+		fake.statuses = append(fake.statuses, &github.RepoStatus{
+			Context: github.String(fmt.Sprintf("ci/job%d", i)), State: github.String("success")})
+	}
+	legacy := &github.RepoStatus{Context: github.String("ci/legacy"), State: github.String("success")}
+	fake.statuses = append(fake.statuses, legacy)
+	if err := tp.waitForChecks(ctx, "main", "abc", time.Minute); err != nil {
+		t.Errorf("error should be nil, but got: %s", err)
+	}
+
+	legacy.State = github.String("failure")
+	if err := tp.waitForChecks(ctx, "main", "abc", time.Minute); err == nil {
+		t.Errorf("error should be returned for the failed check on the second page")
+	}
+}
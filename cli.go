@@ -2,37 +2,196 @@ package tagpr
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/google/go-github/v47/github"
 )
 
 const cmdName = "tagpr"
 
+// output formats of the result of the run
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+// Exit codes of the tagpr command
+const (
+	ExitCodeOK          = 0
+	ExitCodeError       = 1
+	ExitCodeNoChanges   = 2
+	ExitCodeConfigError = 3
+	ExitCodeAPIError    = 4
+)
+
+type exitError struct {
+	err  error
+	code int
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+func (e *exitError) ExitCode() int {
+	return e.code
+}
+
+func exitCode(err error) int {
+	var (
+		errResp      *github.ErrorResponse
+		rateErr      *github.RateLimitError
+		abuseRateErr *github.AbuseRateLimitError
+	)
+	switch {
+	case err == nil:
+		return ExitCodeOK
+	case errors.Is(err, ErrNoChanges), errors.Is(err, ErrVetoed):
+		return ExitCodeNoChanges
+	case errors.Is(err, ErrInvalidConfig), errors.Is(err, ErrInvalidVersionFile):
+		return ExitCodeConfigError
+	case errors.As(err, &errResp), errors.As(err, &rateErr), errors.As(err, &abuseRateErr):
+		return ExitCodeAPIError
+	}
+	return ExitCodeError
+}
+
 func printVersion(out io.Writer) error {
 	_, err := fmt.Fprintf(out, "%s v%s (rev:%s)\n", cmdName, version, revision)
 	return err
 }
 
-// Run the tagpr
+// Run the tagpr. The returned error has the ExitCode method for the exit code of the command.
 func Run(ctx context.Context, argv []string, outStream, errStream io.Writer) error {
+	err := run(ctx, argv, outStream, errStream)
+	if err == nil || err == flag.ErrHelp {
+		return err
+	}
+	return &exitError{err: err, code: exitCode(err)}
+}
+
+const (
+	commandRegenerate = "regenerate"
+	commandPromote    = "promote"
+)
+
+// parseCommand parses the arguments after the flags. "regenerate <version>" to regenerate the
+// release notes and "promote <version>" to promote the candidate tag are available.
+func parseCommand(args []string) (command, version string, err error) {
+	if len(args) == 0 {
+		return "", "", nil
+	}
+	if args[0] != commandRegenerate && args[0] != commandPromote {
+		return "", "", fmt.Errorf("unknown command %q", args[0])
+	}
+	if len(args) != 2 {
+		return "", "", fmt.Errorf("usage: %s [options] %s <version>", cmdName, args[0])
+	}
+	return args[0], args[1], nil
+}
+
+func run(ctx context.Context, argv []string, outStream, errStream io.Writer) error {
 	log.SetOutput(errStream)
 	fs := flag.NewFlagSet(
 		fmt.Sprintf("%s (v%s rev:%s)", cmdName, version, revision), flag.ContinueOnError)
 	fs.SetOutput(errStream)
 	ver := fs.Bool("version", false, "display version")
+	versionOut := fs.String("version-out", "", "file path to write the computed next version")
+	profile := fs.String("profile", "", "profile name to use the [tagpr \"<profile>\"] section of the config")
+	force := fs.Bool("force", false, "bypass the safety checks for emergency releases")
+	repoDir := fs.String("repo-dir", "", "directory in the repository. Defaults to the current directory")
+	output := fs.String("output", outputText, "output format of the result: text or json")
 	if err := fs.Parse(argv); err != nil {
 		return err
 	}
 	if *ver {
 		return printVersion(outStream)
 	}
+	command, cmdVersion, err := parseCommand(fs.Args())
+	if err != nil {
+		return err
+	}
+	if command != "" && *versionOut != "" {
+		return fmt.Errorf("--version-out can't be used with the %s command", command)
+	}
+	if *output != outputText && *output != outputJSON {
+		return fmt.Errorf("invalid --output %q: it must be %q or %q", *output, outputText, outputJSON)
+	}
+	resultStream := outStream
+	if *output == outputJSON {
+		// keep the stdout only for the JSON
+		outStream = errStream
+	}
+
+	// resolve the output path before moving to the root of the repository
+	if *versionOut != "" {
+		p, err := filepath.Abs(*versionOut)
+		if err != nil {
+			return err
+		}
+		*versionOut = p
+	}
+	dir := *repoDir
+	if dir == "" {
+		dir = "."
+	}
+	root, err := findRepoRoot(dir)
+	if err != nil {
+		return err
+	}
+	// The config file and the version files are resolved from the root of the repository.
+	if err := os.Chdir(root); err != nil {
+		return err
+	}
 
 	tp, err := newTagPR(ctx, &commander{
-		gitPath: "git", outStream: outStream, errStream: errStream, dir: "."})
+		gitPath: "git", outStream: outStream, errStream: errStream, dir: "."}, *profile)
 	if err != nil {
 		return err
 	}
-	return tp.Run(ctx)
+	tp.force = *force
+	switch command {
+	case commandRegenerate:
+		tp.regenerate = cmdVersion
+	case commandPromote:
+		tp.promote = cmdVersion
+	}
+	// only the next version is computed for --version-out
+	tp.versionOnly = *versionOut != ""
+	runErr := tp.Run(ctx)
+	// The result is output even if there is nothing to release, that is, the reason is recorded.
+	if runErr != nil && tp.result.reason == "" {
+		if *versionOut != "" {
+			// not to leave the version of the previous run
+			(&result{}).writeVersion(*versionOut)
+		}
+		return runErr
+	}
+	if outFile := os.Getenv("GITHUB_OUTPUT"); outFile != "" {
+		if err := tp.result.writeGitHubOutput(outFile); err != nil {
+			return err
+		}
+	}
+	if *versionOut != "" {
+		// the file is always rewritten, with the empty values if there is nothing to release
+		if err := tp.result.writeVersion(*versionOut); err != nil {
+			return err
+		}
+	}
+	if *output == outputJSON {
+		if err := tp.result.writeJSON(resultStream); err != nil {
+			return err
+		}
+	}
+	return runErr
 }
@@ -0,0 +1,32 @@
+package tagpr
+
+import "testing"
+
+func TestParseCommand(t *testing.T) {
+	testCases := []struct {
+		name    string
+		args    []string
+		command string
+		version string
+		wantErr bool
+	}{
+		{"no command", nil, "", "", false},
+		{"regenerate", []string{"regenerate", "v1.2.3"}, commandRegenerate, "v1.2.3", false},
+		{"regenerate without version", []string{"regenerate"}, "", "", true},
+		{"regenerate with extra args", []string{"regenerate", "v1.2.3", "v1.2.4"}, "", "", true},
+		{"promote", []string{"promote", "v1.2.3"}, commandPromote, "v1.2.3", false},
+		{"promote without version", []string{"promote"}, "", "", true},
+		{"unknown command", []string{"release"}, "", "", true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			command, version, err := parseCommand(tc.args)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("error: %v, wantErr: %t", err, tc.wantErr)
+			}
+			if command != tc.command || version != tc.version {
+				t.Errorf("got: %q %q, expected: %q %q", command, version, tc.command, tc.version)
+			}
+		})
+	}
+}
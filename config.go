@@ -1,8 +1,15 @@
 package tagpr
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
 	"os"
+	"os/exec"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/Songmu/gitconfig"
 	"github.com/google/go-github/v47/github"
@@ -17,6 +24,14 @@ const (
 #       Generally, it is "main." It is the branch for releases. The pcpr tracks this branch,
 #       creates or updates a pull request as a release candidate, or tags when they are merged.
 #
+#   tagpr.prBaseBranch (Optional)
+#       The base branch of the release pull request, if it differs from the release branch.
+#       (e.g. track "develop" and create the pull request into "main") Defaults to the release branch.
+#       The merge of the release pull request is tagged by the run on the base branch.
+#
+#   tagpr.runOnlyOnBranch (Optional)
+#       If specified, the tagpr does nothing and exits successfully unless the current branch is it.
+#
 #   tagpr.versionFile
 #       Versioning file containing the semantic version needed to be updated at release.
 #       It will be synchronized with the "git tag".
@@ -24,6 +39,26 @@ const (
 #       Sometimes the source code file, such as version.go or Bar.pm, is used.
 #       If you do not want to use versioning files but only git tags, specify the "-" string here.
 #       You can specify multiple version files by comma separated strings.
+#       The location of the version can be specified after "#".
+#       (e.g. "config.json#/metadata/version" or "manifest.yaml#spec.version")
+#       Whether the file stores the v-prefix can be specified after "?" (e.g. "VERSION?vPrefix=true")
+#
+#   tagpr.versionFileMissing (Optional)
+#       How to handle the version files that don't exist, "error" (default) or "skip".
+#       If "skip" is specified, missing version files are skipped with a warning.
+#
+#   tagpr.normalizeVersion (Optional)
+#       Flag whether or not to accept the non-standard versions in the version files like "01.02.03"
+#       or "1.2", and to write them in the canonical form on bump.
+#
+#   tagpr.nextDevSuffix (Optional)
+#       Suffix of the development version. (e.g. "-dev" or "-SNAPSHOT")
+#       If specified, the version files are bumped to the next development version like "1.3.1-dev"
+#       on the release branch after tagging.
+#
+#   tagpr.chartKeys (Optional)
+#       Comma separated top-level keys to bump in the Chart.yaml of Helm as the version file.
+#       Defaults to "version". (e.g. "version,appVersion")
 #
 #   tagpr.vPrefix
 #       Flag whether or not v-prefix is added to semver when git tagging. (e.g. v1.2.3 if true)
@@ -32,8 +67,194 @@ const (
 #   tagpr.command (Optional)
 #       Command to change files just before release.
 #
+#   tagpr.commandTimeout (Optional)
+#       Timeout of the command in the Go duration format. (e.g. "5m") No timeout by default.
+#
+#   tagpr.includeCommandOutput (Optional)
+#       Flag whether or not to include the output of the command in the pull request body.
+#
+#   tagpr.showVersionDiff (Optional)
+#       Flag whether or not to include the diff of the version files in the pull request body.
+#
+#   tagpr.deleteBranchAfterMerge (Optional)
+#       Flag whether or not to delete the branch of the release pull request after tagging the merge of it.
+#
+#   tagpr.showClosedIssues (Optional)
+#       Flag whether or not to append the links of the issues closed by each pull request,
+#       referred with the closing keywords in the body of it, to the release notes.
+#
+#   tagpr.tagReleaseOnly (Optional)
+#       Flag whether or not to tag the merge of the release pull request as the candidate like "v1.2.3-rc",
+#       which is promoted to the final tag by "tagpr promote <version>".
+#
+#   tagpr.prComment (Optional)
+#       Flag whether or not to post the summary of the run, the next version and the diff of
+#       the version files, as a comment on the release pull request instead of rewriting the body.
+#
+#   tagpr.versionCommand (Optional)
+#       Command to compute the next version externally. Its stdout is used as the next version
+#       instead of the labels and the other conventions.
+#
+#   tagpr.preflight (Optional)
+#       Command to run before any changes. If it exits with non-zero, the release is vetoed
+#       and the tagpr exits cleanly.
+#
+#   tagpr.bodyCommand (Optional)
+#       Command to transform the pull request body. It receives the body on stdin
+#       and the stdout is used as the new body.
+#
 #   tagpr.tmplate (Optional)
 #       Pull request template in go template format
+#
+#   tagpr.configPrecedence (Optional)
+#       Which of the environment variables ("env", default) and this file ("file") takes
+#       precedence over the other. It is read from this file first.
+#
+#   tagpr.gitPath (Optional)
+#       Path of the git binary used for all the git operations. Defaults to "git" in PATH.
+#
+#   tagpr.prTemplateEngine (Optional)
+#       Engine to render the pull request template, "text" (default) or "html".
+#       The "html" engine escapes the fields for HTML, e.g. "<" in the titles of pull requests.
+#
+#   tagpr.tagPrefix (Optional)
+#       Tag prefix for the version sequence. (e.g. "backend/" for tags like "backend/v1.2.3")
+#
+#   tagpr.tagTemplate (Optional)
+#       Template of tag names in go template format containing {{.Version}} exactly once.
+#       (e.g. "release-v{{.Version}}" or "app@{{.Version}}") tagpr.vPrefix is ignored if specified.
+#       It can't be used with tagpr.tagPrefix.
+#
+#   tagpr.prBranchTemplate (Optional)
+#       Template of the branch name of the release pull request in go template format with
+#       {{.Version}}, the tag name of the next version. (e.g. "release/{{.Version}}")
+#
+#   tagpr.additionalRemotes (Optional)
+#       Comma separated names of the git remotes to push the tag to in addition to the origin,
+#       e.g. mirrors. (e.g. "mirror,backup")
+#
+#   tagpr.tagMessageFromPRBody (Optional)
+#       Flag whether or not to create an annotated tag with the body of the merged pull request
+#       as the tag message.
+#
+#   tagpr.labelPrefixes (Optional)
+#       Comma separated "label=prefix" pairs to prefix the lines of the pull requests with
+#       the label in the release notes. (e.g. "bug=Fix:,enhancement=Feature:")
+#
+#   tagpr.noReleaseLabels (Optional)
+#       Comma separated labels that mean "no release". When the release pull request with
+#       any of them is merged, the tagpr doesn't tag and treats it as a non-release merge.
+#
+#   tagpr.prLabelsRequiredToTag (Optional)
+#       Comma separated labels required to tag. When the release pull request without any of
+#       them is merged, the tagpr doesn't tag as well as tagpr.noReleaseLabels.
+#
+#   tagpr.allowDirtyWorktree (Optional)
+#       Flag whether or not to run even if the working tree has uncommitted changes.
+#       By default, the tagpr stops with an error listing the changed files.
+#
+#   tagpr.amendReleaseCommit (Optional)
+#       Flag whether or not to amend the release commit with the changelog update instead of
+#       adding another commit, so that the release pull request has only one commit by the tagpr.
+#
+#   tagpr.autoUnshallow (Optional)
+#       Flag whether or not to fetch all history by "git fetch --unshallow" when the repository is
+#       a shallow clone. Defaults to true. If false, the tagpr stops with an error on shallow clones.
+#
+#   tagpr.includeDirectCommits (Optional)
+#       Flag whether or not to include the commits pushed directly to the release branch without
+#       pull requests in the release notes.
+#
+#   tagpr.requireNotes (Optional)
+#       Flag whether or not to stop with an error if the release notes are empty.
+#
+#   tagpr.breakingLabels (Optional)
+#       Labels of the pull requests called out in the "Breaking Changes" section at the top of
+#       the release notes. Disabled by default. Multiple labels can be specified separated by commas.
+#
+#   tagpr.highlightReactions (Optional)
+#       Number of the :+1: reactions to call out the merged pull request in the "Highlights"
+#       section of the release notes. Disabled by default.
+#
+#   tagpr.recentReleasesCount (Optional)
+#       Number of the previous releases linked in the "Previous Releases" section at the end of
+#       the GitHub release. Disabled by default.
+#
+#   tagpr.majorOnBreaking (Optional)
+#       Flag whether or not to bump the major version if any of the merged pull requests have
+#       tagpr.breakingLabels. It has no effect without tagpr.breakingLabels.
+#
+#   tagpr.mergeMethod (Optional)
+#       Desired merge method of the release pull request, "merge", "squash" or "rebase".
+#       It is recorded in the pull request body for integrations merging it.
+#
+#   tagpr.autoMerge (Optional)
+#       Flag whether or not to enable the auto-merge of the release pull request with tagpr.mergeMethod.
+#       ("merge" by default)
+#
+#   tagpr.requireChecklist (Optional)
+#       Flag whether or not to refuse to tag if the merged release pull request has unchecked task items.
+#
+#   tagpr.releaseAssets (Optional)
+#       Comma separated glob patterns of the files to upload to the GitHub release as assets.
+#       (e.g. "dist/*.tar.gz,dist/*.zip")
+#
+#   tagpr.checksumsFile (Optional)
+#       Path of the checksums file of the artifacts to embed in the GitHub release notes.
+#       (e.g. "dist/checksums.txt") It is skipped with a warning if it doesn't exist at tagging.
+#
+#   tagpr.waitForChecks (Optional)
+#       Flag whether or not to wait for the required status checks of the release branch to pass
+#       on the merge commit before tagging.
+#
+#   tagpr.checksTimeout (Optional)
+#       Timeout to wait for the required status checks in the Go duration format. Defaults to "10m".
+#
+#   tagpr.freezeCron (Optional)
+#       Cron expression of the freeze window, e.g. "* * * * 0,6" for weekends. The tagpr refuses
+#       to tag in the window, but still updates the release pull request.
+#
+#   tagpr.freezeDates (Optional)
+#       Ranges of the dates of the freeze windows like "2026-12-24..2027-01-03". Multiple values can be
+#       specified separated by commas.
+#
+#   tagpr.prAutoCloseStale (Optional)
+#       Flag whether or not to close the open release pull requests superseded by the current one.
+#
+#   tagpr.milestone (Optional)
+#       If "auto" is specified, the tagpr finds or creates the milestone named after the next version,
+#       attaches the release pull request and the included pull requests to it, and closes it on release.
+#
+#   tagpr.notesSort (Optional)
+#       Order of the pull requests in each section of the release notes, "mergedAt", "number"
+#       or "title". The order of GitHub is kept by default.
+#
+#   tagpr.notesFormat (Optional)
+#       Format of the changelog file, "markdown" (default) for CHANGELOG.md or "rst" for
+#       CHANGELOG.rst in reStructuredText, e.g. for Sphinx.
+#
+#   tagpr.changelogLinkTemplate (Optional)
+#       Template of the heading of each version in the CHANGELOG.md in go template format.
+#       The fields are .Tag, .Link (the comparison link), .Date and .Anchor.
+#       (e.g. "<a id=\"{{.Anchor}}\"></a>\n## [{{.Tag}}]({{.Link}}) - {{.Date}}")
+#
+#   tagpr.owner, tagpr.repo, tagpr.host (Optional)
+#       Owner, name and host of the GitHub repository. They are detected from the remote URL
+#       by default. Specify them if the remote uses an SSH host alias or something unusual.
+#
+#   tagpr.userAgent (Optional)
+#       User-Agent of the requests to the GitHub API. Defaults to "tagpr/<version>" with the URL
+#       of the tagpr, to identify the traffic of the tagpr in the audit logs.
+#
+#   tagpr.titleBumpPattern (Optional)
+#       Regular expression with a capture group to detect the version bump from the titles
+#       of the release pull request and the merged pull requests. (e.g. "^\\[(major|minor|patch)\\]")
+#       The labels on the release pull request take precedence over it.
+#
+# PROFILES:
+#   Multiple independent version sequences can be managed in one repository by named
+#   sections like [tagpr "backend"]. The section is selected by the "--profile" flag,
+#   and the settings in it take precedence over the ones in the [tagpr] section.
 [tagpr]
 `
 	envReleaseBranch    = "TAGPR_RELEASE_BRANCH"
@@ -41,11 +262,165 @@ const (
 	envVPrefix          = "TAGPR_VPREFIX"
 	envCommand          = "TAGPR_COMMAND"
 	envTemplate         = "TAGPR_TEMPLATE"
+	envTagPrefix        = "TAGPR_TAG_PREFIX"
 	configReleaseBranch = "tagpr.releaseBranch"
 	configVersionFile   = "tagpr.versionFile"
 	configVPrefix       = "tagpr.vPrefix"
 	configCommand       = "tagpr.command"
 	configTemplate      = "tagpr.template"
+	configTagPrefix     = "tagpr.tagPrefix"
+
+	// The push token is read only from the environment variable not to be committed.
+	envPushToken = "TAGPR_PUSH_TOKEN"
+
+	envTagMessageFromPRBody      = "TAGPR_TAG_MESSAGE_FROM_PR_BODY"
+	configTagMessageFromPRBody   = "tagpr.tagMessageFromPRBody"
+	envAllowDirtyWorktree        = "TAGPR_ALLOW_DIRTY_WORKTREE"
+	configAllowDirtyWorktree     = "tagpr.allowDirtyWorktree"
+	envAmendReleaseCommit        = "TAGPR_AMEND_RELEASE_COMMIT"
+	configAmendReleaseCommit     = "tagpr.amendReleaseCommit"
+	envAutoUnshallow             = "TAGPR_AUTO_UNSHALLOW"
+	configAutoUnshallow          = "tagpr.autoUnshallow"
+	envIncludeCommandOutput      = "TAGPR_INCLUDE_COMMAND_OUTPUT"
+	configIncludeCommandOutput   = "tagpr.includeCommandOutput"
+	envAutoMerge                 = "TAGPR_AUTO_MERGE"
+	configAutoMerge              = "tagpr.autoMerge"
+	envIncludeDirectCommits      = "TAGPR_INCLUDE_DIRECT_COMMITS"
+	configIncludeDirectCommits   = "tagpr.includeDirectCommits"
+	envRequireNotes              = "TAGPR_REQUIRE_NOTES"
+	configRequireNotes           = "tagpr.requireNotes"
+	envPRAutoCloseStale          = "TAGPR_PR_AUTO_CLOSE_STALE"
+	configPRAutoCloseStale       = "tagpr.prAutoCloseStale"
+	envRequireChecklist          = "TAGPR_REQUIRE_CHECKLIST"
+	configRequireChecklist       = "tagpr.requireChecklist"
+	envWaitForChecks             = "TAGPR_WAIT_FOR_CHECKS"
+	configWaitForChecks          = "tagpr.waitForChecks"
+	envMajorOnBreaking           = "TAGPR_MAJOR_ON_BREAKING"
+	configMajorOnBreaking        = "tagpr.majorOnBreaking"
+	envShowVersionDiff           = "TAGPR_SHOW_VERSION_DIFF"
+	configShowVersionDiff        = "tagpr.showVersionDiff"
+	envNormalizeVersion          = "TAGPR_NORMALIZE_VERSION"
+	configNormalizeVersion       = "tagpr.normalizeVersion"
+	envPRComment                 = "TAGPR_PR_COMMENT"
+	configPRComment              = "tagpr.prComment"
+	envDeleteBranchAfterMerge    = "TAGPR_DELETE_BRANCH_AFTER_MERGE"
+	configDeleteBranchAfterMerge = "tagpr.deleteBranchAfterMerge"
+	envShowClosedIssues          = "TAGPR_SHOW_CLOSED_ISSUES"
+	configShowClosedIssues       = "tagpr.showClosedIssues"
+	envTagReleaseOnly            = "TAGPR_TAG_RELEASE_ONLY"
+	configTagReleaseOnly         = "tagpr.tagReleaseOnly"
+
+	envBreakingLabels    = "TAGPR_BREAKING_LABELS"
+	configBreakingLabels = "tagpr.breakingLabels"
+
+	envTitleBumpPattern    = "TAGPR_TITLE_BUMP_PATTERN"
+	configTitleBumpPattern = "tagpr.titleBumpPattern"
+
+	envPRBaseBranch    = "TAGPR_PR_BASE_BRANCH"
+	configPRBaseBranch = "tagpr.prBaseBranch"
+
+	envNoReleaseLabels    = "TAGPR_NO_RELEASE_LABELS"
+	configNoReleaseLabels = "tagpr.noReleaseLabels"
+
+	envPRLabelsRequiredToTag    = "TAGPR_PR_LABELS_REQUIRED_TO_TAG"
+	configPRLabelsRequiredToTag = "tagpr.prLabelsRequiredToTag"
+
+	envLabelPrefixes    = "TAGPR_LABEL_PREFIXES"
+	configLabelPrefixes = "tagpr.labelPrefixes"
+
+	envChecksTimeout    = "TAGPR_CHECKS_TIMEOUT"
+	configChecksTimeout = "tagpr.checksTimeout"
+
+	envConfigPrecedence    = "TAGPR_CONFIG_PRECEDENCE"
+	configConfigPrecedence = "tagpr.configPrecedence"
+
+	envHighlightReactions    = "TAGPR_HIGHLIGHT_REACTIONS"
+	configHighlightReactions = "tagpr.highlightReactions"
+
+	envRecentReleasesCount    = "TAGPR_RECENT_RELEASES_COUNT"
+	configRecentReleasesCount = "tagpr.recentReleasesCount"
+
+	envGitPath    = "TAGPR_GIT_PATH"
+	configGitPath = "tagpr.gitPath"
+
+	envPRTemplateEngine    = "TAGPR_PR_TEMPLATE_ENGINE"
+	configPRTemplateEngine = "tagpr.prTemplateEngine"
+
+	envTagExisting    = "TAGPR_TAG_EXISTING"
+	configTagExisting = "tagpr.tagExisting"
+
+	envFreezeCron    = "TAGPR_FREEZE_CRON"
+	configFreezeCron = "tagpr.freezeCron"
+
+	envFreezeDates    = "TAGPR_FREEZE_DATES"
+	configFreezeDates = "tagpr.freezeDates"
+
+	envReleaseAssets    = "TAGPR_RELEASE_ASSETS"
+	configReleaseAssets = "tagpr.releaseAssets"
+
+	envChecksumsFile    = "TAGPR_CHECKSUMS_FILE"
+	configChecksumsFile = "tagpr.checksumsFile"
+
+	envChartKeys    = "TAGPR_CHART_KEYS"
+	configChartKeys = "tagpr.chartKeys"
+
+	envRunOnlyOnBranch    = "TAGPR_RUN_ONLY_ON_BRANCH"
+	configRunOnlyOnBranch = "tagpr.runOnlyOnBranch"
+
+	envAdditionalRemotes    = "TAGPR_ADDITIONAL_REMOTES"
+	configAdditionalRemotes = "tagpr.additionalRemotes"
+
+	envVersionCommand    = "TAGPR_VERSION_COMMAND"
+	configVersionCommand = "tagpr.versionCommand"
+
+	envPreflight    = "TAGPR_PREFLIGHT"
+	configPreflight = "tagpr.preflight"
+
+	envBodyCommand    = "TAGPR_BODY_COMMAND"
+	configBodyCommand = "tagpr.bodyCommand"
+
+	envCommandTimeout    = "TAGPR_COMMAND_TIMEOUT"
+	configCommandTimeout = "tagpr.commandTimeout"
+
+	envMergeMethod    = "TAGPR_MERGE_METHOD"
+	configMergeMethod = "tagpr.mergeMethod"
+
+	envMilestone    = "TAGPR_MILESTONE"
+	configMilestone = "tagpr.milestone"
+
+	envNextDevSuffix    = "TAGPR_NEXT_DEV_SUFFIX"
+	configNextDevSuffix = "tagpr.nextDevSuffix"
+
+	envOwner    = "TAGPR_OWNER"
+	configOwner = "tagpr.owner"
+	envRepo     = "TAGPR_REPO"
+	configRepo  = "tagpr.repo"
+	envHost     = "TAGPR_HOST"
+	configHost  = "tagpr.host"
+
+	envUserAgent    = "TAGPR_USER_AGENT"
+	configUserAgent = "tagpr.userAgent"
+
+	envNotesSort    = "TAGPR_NOTES_SORT"
+	configNotesSort = "tagpr.notesSort"
+
+	envNotesFormat    = "TAGPR_NOTES_FORMAT"
+	configNotesFormat = "tagpr.notesFormat"
+
+	envChangelogLinkTemplate    = "TAGPR_CHANGELOG_LINK_TEMPLATE"
+	configChangelogLinkTemplate = "tagpr.changelogLinkTemplate"
+
+	envTagTemplate    = "TAGPR_TAG_TEMPLATE"
+	configTagTemplate = "tagpr.tagTemplate"
+
+	envPRBranchTemplate    = "TAGPR_PR_BRANCH_TEMPLATE"
+	configPRBranchTemplate = "tagpr.prBranchTemplate"
+
+	envVersionFileMissing    = "TAGPR_VERSION_FILE_MISSING"
+	configVersionFileMissing = "tagpr.versionFileMissing"
+
+	versionFileMissingError = "error"
+	versionFileMissingSkip  = "skip"
 )
 
 type config struct {
@@ -53,98 +428,326 @@ type config struct {
 	versionFile   *configValue
 	command       *configValue
 	template      *configValue
+	tagPrefix     *configValue
+	titleBump     *configValue
+	vfMissing     *configValue
+	prBaseBranch  *configValue
+	noRelLabels   *configValue
+	reqLabels     *configValue
+	nextDevSuffix *configValue
+	comTimeout    *configValue
+	mergeMethod   *configValue
+	milestone     *configValue
+	tagTemplate   *configValue
+	owner         *configValue
+	repo          *configValue
+	host          *configValue
+	changelogLink *configValue
+	labelPrefixes *configValue
+	bodyCommand   *configValue
+	verCommand    *configValue
+	preflight     *configValue
+	addRemotes    *configValue
+	runOnlyOn     *configValue
+	chartKeys     *configValue
+	checksums     *configValue
+	relAssets     *configValue
+	checksTimeout *configValue
+	freezeCron    *configValue
+	freezeDates   *configValue
+	breakLabels   *configValue
+	tagExisting   *configValue
+	tmplEngine    *configValue
+	gitBin        *configValue
+	highlights    *configValue
+	notesFmt      *configValue
+	notesSort     *configValue
+	prBranchTmpl  *configValue
+	recentRels    *configValue
+	userAgent     *configValue
 	vPrefix       *bool
 
+	tagMessageFromPRBody *bool
+	allowDirtyWorktree   *bool
+	amendReleaseCommit   *bool
+	autoUnshallow        *bool
+	includeComOutput     *bool
+	autoMerge            *bool
+	includeDirectCommits *bool
+	requireNotes         *bool
+	prAutoCloseStale     *bool
+	requireChecklist     *bool
+	waitForChecks        *bool
+	majorOnBreaking      *bool
+	showVersionDiff      *bool
+	normalizeVersion     *bool
+	prComment            *bool
+	deleteBranch         *bool
+	showClosedIssues     *bool
+	tagReleaseOnly       *bool
+
 	conf      string
+	profile   string
 	gitconfig *gitconfig.Config
+	gitPath   string
+	// preferFile is whether the values in the config file take precedence over the environment variables
+	preferFile bool
+	// readErrLogged is whether the read error of the config file is already logged in Reload
+	readErrLogged bool
 }
 
-func newConfig(gitPath string) (*config, error) {
+func newConfig(gitPath, profile string) (*config, error) {
 	cfg := &config{
 		conf:      defaultConfigFile,
+		profile:   profile,
 		gitconfig: &gitconfig.Config{GitPath: gitPath, File: defaultConfigFile},
+		gitPath:   gitPath,
 	}
 	err := cfg.Reload()
 	return cfg, err
 }
 
 func (cfg *config) Reload() error {
-	if rb := os.Getenv(envReleaseBranch); rb != "" {
-		cfg.releaseBranch = &configValue{
-			value:  rb,
-			source: srcEnv,
-		}
-	} else {
-		out, err := cfg.gitconfig.Get(configReleaseBranch)
-		if err == nil {
-			cfg.releaseBranch = &configValue{
-				value:  out,
-				source: srcConfigFile,
-			}
-		}
+	cfg.readErrLogged = false
+	if err := cfg.loadPrecedence(); err != nil {
+		return err
+	}
+	cfg.releaseBranch = cfg.getValue(envReleaseBranch, configReleaseBranch)
+	cfg.versionFile = cfg.getValue(envVersionFile, configVersionFile)
+	cfg.command = cfg.getValue(envCommand, configCommand)
+	cfg.template = cfg.getValue(envTemplate, configTemplate)
+	cfg.tagPrefix = cfg.getValue(envTagPrefix, configTagPrefix)
+	cfg.titleBump = cfg.getValue(envTitleBumpPattern, configTitleBumpPattern)
+	cfg.vfMissing = cfg.getValue(envVersionFileMissing, configVersionFileMissing)
+	cfg.prBaseBranch = cfg.getValue(envPRBaseBranch, configPRBaseBranch)
+	cfg.noRelLabels = cfg.getValue(envNoReleaseLabels, configNoReleaseLabels)
+	cfg.reqLabels = cfg.getValue(envPRLabelsRequiredToTag, configPRLabelsRequiredToTag)
+	cfg.nextDevSuffix = cfg.getValue(envNextDevSuffix, configNextDevSuffix)
+	cfg.comTimeout = cfg.getValue(envCommandTimeout, configCommandTimeout)
+	cfg.mergeMethod = cfg.getValue(envMergeMethod, configMergeMethod)
+	cfg.milestone = cfg.getValue(envMilestone, configMilestone)
+	cfg.tagTemplate = cfg.getValue(envTagTemplate, configTagTemplate)
+	cfg.owner = cfg.getValue(envOwner, configOwner)
+	cfg.repo = cfg.getValue(envRepo, configRepo)
+	cfg.host = cfg.getValue(envHost, configHost)
+	cfg.changelogLink = cfg.getValue(envChangelogLinkTemplate, configChangelogLinkTemplate)
+	cfg.labelPrefixes = cfg.getValue(envLabelPrefixes, configLabelPrefixes)
+	cfg.bodyCommand = cfg.getValue(envBodyCommand, configBodyCommand)
+	cfg.verCommand = cfg.getValue(envVersionCommand, configVersionCommand)
+	cfg.preflight = cfg.getValue(envPreflight, configPreflight)
+	cfg.addRemotes = cfg.getValue(envAdditionalRemotes, configAdditionalRemotes)
+	cfg.runOnlyOn = cfg.getValue(envRunOnlyOnBranch, configRunOnlyOnBranch)
+	cfg.chartKeys = cfg.getValue(envChartKeys, configChartKeys)
+	cfg.checksums = cfg.getValue(envChecksumsFile, configChecksumsFile)
+	cfg.relAssets = cfg.getValue(envReleaseAssets, configReleaseAssets)
+	cfg.checksTimeout = cfg.getValue(envChecksTimeout, configChecksTimeout)
+	cfg.freezeCron = cfg.getValue(envFreezeCron, configFreezeCron)
+	cfg.freezeDates = cfg.getValue(envFreezeDates, configFreezeDates)
+	cfg.breakLabels = cfg.getValue(envBreakingLabels, configBreakingLabels)
+	cfg.tagExisting = cfg.getValue(envTagExisting, configTagExisting)
+	cfg.tmplEngine = cfg.getValue(envPRTemplateEngine, configPRTemplateEngine)
+	cfg.gitBin = cfg.getValue(envGitPath, configGitPath)
+	cfg.highlights = cfg.getValue(envHighlightReactions, configHighlightReactions)
+	cfg.notesFmt = cfg.getValue(envNotesFormat, configNotesFormat)
+	cfg.notesSort = cfg.getValue(envNotesSort, configNotesSort)
+	cfg.prBranchTmpl = cfg.getValue(envPRBranchTemplate, configPRBranchTemplate)
+	cfg.recentRels = cfg.getValue(envRecentReleasesCount, configRecentReleasesCount)
+	cfg.userAgent = cfg.getValue(envUserAgent, configUserAgent)
+	if ms := cfg.Milestone(); ms != "" && ms != milestoneAuto {
+		return fmt.Errorf("%w: %s: only %q is supported: %q", ErrInvalidConfig, configMilestone, milestoneAuto, ms)
+	}
+	if mm := cfg.MergeMethod(); mm != "" &&
+		mm != mergeMethodMerge && mm != mergeMethodSquash && mm != mergeMethodRebase {
+		return fmt.Errorf("%w: %s: %q", ErrInvalidConfig, configMergeMethod, mm)
 	}
 
-	if rb := os.Getenv(envVersionFile); rb != "" {
-		cfg.versionFile = &configValue{
-			value:  rb,
-			source: srcEnv,
-		}
-	} else {
-		out, err := cfg.gitconfig.Get(configVersionFile)
-		if err == nil {
-			cfg.versionFile = &configValue{
-				value:  out,
-				source: srcConfigFile,
-			}
-		}
+	var err error
+	if cfg.vPrefix, err = cfg.getBool(envVPrefix, configVPrefix); err != nil {
+		return err
+	}
+	if cfg.tagMessageFromPRBody, err = cfg.getBool(envTagMessageFromPRBody, configTagMessageFromPRBody); err != nil {
+		return err
+	}
+	if cfg.allowDirtyWorktree, err = cfg.getBool(envAllowDirtyWorktree, configAllowDirtyWorktree); err != nil {
+		return err
+	}
+	if cfg.amendReleaseCommit, err = cfg.getBool(envAmendReleaseCommit, configAmendReleaseCommit); err != nil {
+		return err
+	}
+	if cfg.autoUnshallow, err = cfg.getBool(envAutoUnshallow, configAutoUnshallow); err != nil {
+		return err
+	}
+	if cfg.includeComOutput, err = cfg.getBool(envIncludeCommandOutput, configIncludeCommandOutput); err != nil {
+		return err
+	}
+	if cfg.autoMerge, err = cfg.getBool(envAutoMerge, configAutoMerge); err != nil {
+		return err
+	}
+	if cfg.includeDirectCommits, err = cfg.getBool(envIncludeDirectCommits, configIncludeDirectCommits); err != nil {
+		return err
 	}
+	if cfg.requireNotes, err = cfg.getBool(envRequireNotes, configRequireNotes); err != nil {
+		return err
+	}
+	if cfg.prAutoCloseStale, err = cfg.getBool(envPRAutoCloseStale, configPRAutoCloseStale); err != nil {
+		return err
+	}
+	if cfg.requireChecklist, err = cfg.getBool(envRequireChecklist, configRequireChecklist); err != nil {
+		return err
+	}
+	if cfg.waitForChecks, err = cfg.getBool(envWaitForChecks, configWaitForChecks); err != nil {
+		return err
+	}
+	if cfg.majorOnBreaking, err = cfg.getBool(envMajorOnBreaking, configMajorOnBreaking); err != nil {
+		return err
+	}
+	if cfg.showVersionDiff, err = cfg.getBool(envShowVersionDiff, configShowVersionDiff); err != nil {
+		return err
+	}
+	if cfg.normalizeVersion, err = cfg.getBool(envNormalizeVersion, configNormalizeVersion); err != nil {
+		return err
+	}
+	if cfg.prComment, err = cfg.getBool(envPRComment, configPRComment); err != nil {
+		return err
+	}
+	if cfg.deleteBranch, err = cfg.getBool(envDeleteBranchAfterMerge, configDeleteBranchAfterMerge); err != nil {
+		return err
+	}
+	if cfg.showClosedIssues, err = cfg.getBool(envShowClosedIssues, configShowClosedIssues); err != nil {
+		return err
+	}
+	if cfg.tagReleaseOnly, err = cfg.getBool(envTagReleaseOnly, configTagReleaseOnly); err != nil {
+		return err
+	}
+	return nil
+}
 
-	if vPrefix := os.Getenv(envVPrefix); vPrefix != "" {
-		b, err := strconv.ParseBool(vPrefix)
-		if err != nil {
-			return err
-		}
-		cfg.vPrefix = github.Bool(b)
-	} else {
-		b, err := cfg.gitconfig.Bool(configVPrefix)
-		if err == nil {
-			cfg.vPrefix = github.Bool(b)
-		}
+// key returns the config key for the current profile. Profile settings are
+// stored in the subsection of the profile name, e.g. "tagpr.backend.releaseBranch".
+func (cfg *config) key(k string) string {
+	if cfg.profile == "" {
+		return k
 	}
+	return "tagpr." + cfg.profile + "." + strings.TrimPrefix(k, "tagpr.")
+}
 
-	if command := os.Getenv(envCommand); command != "" {
-		cfg.command = &configValue{
-			value:  command,
-			source: srcEnv,
-		}
-	} else {
-		command, err := cfg.gitconfig.Get(configCommand)
-		if err == nil {
-			cfg.command = &configValue{
-				value:  command,
-				source: srcConfigFile,
-			}
+// getValue retrieves the value from the environment variable or the config file.
+// When a profile is specified, the value in the profile section takes precedence
+// over the one in the [tagpr] section.
+func (cfg *config) getValue(envKey, confKey string) *configValue {
+	if cfg.preferFile {
+		if v := cfg.getFileValue(confKey); v != nil {
+			return v
 		}
+		return getEnvValue(envKey)
+	}
+	if v := getEnvValue(envKey); v != nil {
+		return v
 	}
+	return cfg.getFileValue(confKey)
+}
 
-	if tmpl := os.Getenv(envTemplate); tmpl != "" {
-		cfg.template = &configValue{
-			value:  tmpl,
+func getEnvValue(envKey string) *configValue {
+	if v := os.Getenv(envKey); v != "" {
+		return &configValue{
+			value:  v,
 			source: srcEnv,
 		}
-	} else {
-		template, err := cfg.gitconfig.Get(configTemplate)
-		if err == nil {
-			cfg.template = &configValue{
-				value:  template,
-				source: srcConfigFile,
-			}
+	}
+	return nil
+}
+
+func (cfg *config) getFileValue(confKey string) *configValue {
+	out, err := cfg.gitconfig.Get(cfg.key(confKey))
+	if err != nil && cfg.profile != "" {
+		out, err = cfg.gitconfig.Get(confKey)
+	}
+	if err != nil {
+		cfg.logReadError(confKey, err, "--get")
+		return nil
+	}
+	return &configValue{
+		value:  out,
+		source: srcConfigFile,
+	}
+}
+
+// getBool retrieves the boolean value from the environment variable or the config file.
+// It returns nil if the value is not set, to distinguish it from the explicit false
+// for the auto-detection like tagpr.vPrefix.
+func (cfg *config) getBool(envKey, confKey string) (*bool, error) {
+	if cfg.preferFile {
+		if b := cfg.getFileBool(confKey); b != nil {
+			return b, nil
 		}
+		return getEnvBool(envKey)
+	}
+	if b, err := getEnvBool(envKey); b != nil || err != nil {
+		return b, err
+	}
+	return cfg.getFileBool(confKey), nil
+}
+
+func getEnvBool(envKey string) (*bool, error) {
+	v := os.Getenv(envKey)
+	if v == "" {
+		return nil, nil
 	}
+	b, err := parseBool(v)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %s", ErrInvalidConfig, envKey, err)
+	}
+	return github.Bool(b), nil
+}
+
+func (cfg *config) getFileBool(confKey string) *bool {
+	b, err := cfg.gitconfig.Bool(cfg.key(confKey))
+	if err != nil && cfg.profile != "" {
+		b, err = cfg.gitconfig.Bool(confKey)
+	}
+	if err != nil {
+		cfg.logReadError(confKey, err, "--get", "--bool")
+		return nil
+	}
+	return github.Bool(b)
+}
+
+const (
+	precedenceEnv  = "env"
+	precedenceFile = "file"
+)
 
+// loadPrecedence loads tagpr.configPrecedence. It is read from the config file first,
+// so that the file stays authoritative even if TAGPR_CONFIG_PRECEDENCE leaks into the runner.
+func (cfg *config) loadPrecedence() error {
+	cfg.preferFile = true
+	v := cfg.getValue(envConfigPrecedence, configConfigPrecedence)
+	cfg.preferFile = false
+	if v == nil || v.Empty() {
+		return nil
+	}
+	switch p := strings.ToLower(v.String()); p {
+	case precedenceEnv:
+	case precedenceFile:
+		cfg.preferFile = true
+	default:
+		return fmt.Errorf("%w: %s: %q", ErrInvalidConfig, configConfigPrecedence, v.String())
+	}
 	return nil
 }
 
+// parseBool parses the boolean value of the environment variable. It accepts "yes", "no", "on"
+// and "off" as well as git config, in addition to the ones of strconv.ParseBool.
+func parseBool(v string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "yes", "on":
+		return true, nil
+	case "no", "off":
+		return false, nil
+	}
+	return strconv.ParseBool(strings.TrimSpace(v))
+}
+
 func (cfg *config) set(key, value string) error {
 	if !exists(cfg.conf) {
 		if err := cfg.initializeFile(); err != nil {
@@ -154,6 +757,7 @@ func (cfg *config) set(key, value string) error {
 	if value == "" {
 		value = "-" // value "-" represents null (really?)
 	}
+	key = cfg.key(key)
 	_, err := cfg.gitconfig.Do(key, value)
 	if err != nil {
 		// in this case, config file might be invalid or broken, so retry once.
@@ -162,7 +766,43 @@ func (cfg *config) set(key, value string) error {
 		}
 		_, err = cfg.gitconfig.Do(key, value)
 	}
-	return err
+	if err != nil {
+		return cfg.gitconfigError("write", key, err)
+	}
+	return nil
+}
+
+// logReadError logs the error of reading the config file other than the missing key. The value
+// is treated as unset in that case, so only the first one is logged not to flood the same error
+// for each key, e.g. of the broken file.
+func (cfg *config) logReadError(key string, err error, readArgs ...string) {
+	if gitconfig.IsNotFound(err) || cfg.readErrLogged || !exists(cfg.conf) {
+		return
+	}
+	cfg.readErrLogged = true
+	log.Printf("ignored the config: %s\n", cfg.gitconfigError("read", key, err, readArgs...))
+}
+
+// gitconfigError wraps the error of git config with the operation, the key and the stderr of git.
+// As gitconfig doesn't capture the stderr, it is retrieved by listing the config file again, and
+// by reading the key again with readArgs if the file itself is valid, e.g. for a non-boolean value.
+func (cfg *config) gitconfigError(op, key string, err error, readArgs ...string) error {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		var stderr bytes.Buffer
+		cmd := exec.Command(cfg.gitPath, "config", "--file", cfg.conf, "--list")
+		cmd.Stderr = &stderr
+		if cmd.Run() == nil && len(readArgs) > 0 {
+			args := append([]string{"config", "--file", cfg.conf}, readArgs...)
+			cmd = exec.Command(cfg.gitPath, append(args, key)...)
+			cmd.Stderr = &stderr
+			cmd.Run()
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("failed to %s %s in %s: %w: %s", op, key, cfg.conf, err, msg)
+		}
+	}
+	return fmt.Errorf("failed to %s %s in %s: %w", op, key, cfg.conf, err)
 }
 
 func (cfg *config) initializeFile() error {
@@ -217,10 +857,394 @@ func (cfg *config) Command() *configValue {
 	return cfg.command
 }
 
+// CommandTimeout returns the timeout of tagpr.command. Zero means no timeout.
+func (cfg *config) CommandTimeout() (time.Duration, error) {
+	if cfg.comTimeout == nil || cfg.comTimeout.Empty() {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(cfg.comTimeout.String())
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s: %s", ErrInvalidConfig, configCommandTimeout, err)
+	}
+	return d, nil
+}
+
 func (cfg *config) Template() *configValue {
 	return cfg.template
 }
 
+func (cfg *config) GitPath() string {
+	if cfg.gitBin == nil {
+		return ""
+	}
+	return cfg.gitBin.String()
+}
+
+// setGitPath switches the git used to read and write the config file
+func (cfg *config) setGitPath(gitPath string) {
+	cfg.gitPath = gitPath
+	cfg.gitconfig.GitPath = gitPath
+}
+
+// PRTemplateEngine returns the engine to render the pull request template. Defaults to "text".
+func (cfg *config) PRTemplateEngine() (string, error) {
+	if cfg.tmplEngine == nil || cfg.tmplEngine.Empty() {
+		return tmplEngineText, nil
+	}
+	engine := cfg.tmplEngine.String()
+	if engine != tmplEngineText && engine != tmplEngineHTML {
+		return "", fmt.Errorf("%w: %s: %q", ErrInvalidConfig, configPRTemplateEngine, engine)
+	}
+	return engine, nil
+}
+
+// NotesSort returns the order of the pull requests in the release notes. Empty means the order of GitHub.
+func (cfg *config) NotesSort() (string, error) {
+	if cfg.notesSort == nil || cfg.notesSort.Empty() {
+		return "", nil
+	}
+	switch by := cfg.notesSort.String(); by {
+	case notesSortMergedAt, notesSortNumber, notesSortTitle:
+		return by, nil
+	default:
+		return "", fmt.Errorf("%w: %s: %q", ErrInvalidConfig, configNotesSort, by)
+	}
+}
+
+// NotesFormat returns the format of the changelog file. Defaults to markdown.
+func (cfg *config) NotesFormat() (notesFormat, error) {
+	if cfg.notesFmt == nil || cfg.notesFmt.Empty() {
+		return newNotesFormat(notesFormatMarkdown), nil
+	}
+	name := cfg.notesFmt.String()
+	if name != notesFormatMarkdown && name != notesFormatRST {
+		return nil, fmt.Errorf("%w: %s: %q", ErrInvalidConfig, configNotesFormat, name)
+	}
+	return newNotesFormat(name), nil
+}
+
+func (cfg *config) PRBaseBranch() *configValue {
+	return cfg.prBaseBranch
+}
+
+func (cfg *config) NoReleaseLabels() []string {
+	return cfg.noRelLabels.List()
+}
+
+func (cfg *config) PRLabelsRequiredToTag() []string {
+	return cfg.reqLabels.List()
+}
+
+type labelPrefix struct {
+	label, prefix string
+}
+
+// LabelPrefixes returns the pairs of the label and the prefix in the configured order
+func (cfg *config) LabelPrefixes() ([]labelPrefix, error) {
+	var lps []labelPrefix
+	for _, pair := range cfg.labelPrefixes.List() {
+		stuffs := strings.SplitN(pair, "=", 2)
+		if len(stuffs) != 2 || strings.TrimSpace(stuffs[0]) == "" {
+			return nil, fmt.Errorf("%w: %s: must be label=prefix pairs: %q", ErrInvalidConfig, configLabelPrefixes, pair)
+		}
+		lps = append(lps, labelPrefix{label: strings.TrimSpace(stuffs[0]), prefix: strings.TrimSpace(stuffs[1])})
+	}
+	return lps, nil
+}
+
+func (cfg *config) ReleaseAssets() []string {
+	return cfg.relAssets.List()
+}
+
+// UserAgent returns the User-Agent of the requests to the GitHub API.
+// Defaults to "tagpr/<version> (+https://github.com/Songmu/tagpr)".
+func (cfg *config) UserAgent() string {
+	if cfg.userAgent == nil || cfg.userAgent.String() == "" {
+		return fmt.Sprintf("%s/%s (+https://github.com/Songmu/tagpr)", cmdName, version)
+	}
+	return cfg.userAgent.String()
+}
+
+func (cfg *config) ChecksumsFile() string {
+	if cfg.checksums == nil {
+		return ""
+	}
+	return cfg.checksums.String()
+}
+
+// ChartKeys returns the keys to bump in Chart.yaml. Defaults to "version".
+func (cfg *config) ChartKeys() []string {
+	if keys := cfg.chartKeys.List(); len(keys) > 0 {
+		return keys
+	}
+	return []string{"version"}
+}
+
+func (cfg *config) BreakingLabels() []string {
+	return cfg.breakLabels.List()
+}
+
+// HighlightReactions returns the number of the :+1: reactions to highlight the pull request.
+// Zero means disabled.
+func (cfg *config) HighlightReactions() (int, error) {
+	if cfg.highlights == nil || cfg.highlights.Empty() {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(cfg.highlights.String())
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%w: %s: %q", ErrInvalidConfig, configHighlightReactions, cfg.highlights.String())
+	}
+	return n, nil
+}
+
+// RecentReleasesCount returns the number of the previous releases linked in the GitHub release.
+// Zero means disabled.
+func (cfg *config) RecentReleasesCount() (int, error) {
+	if cfg.recentRels == nil || cfg.recentRels.Empty() {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(cfg.recentRels.String())
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%w: %s: %q", ErrInvalidConfig, configRecentReleasesCount, cfg.recentRels.String())
+	}
+	return n, nil
+}
+
+func (cfg *config) MajorOnBreaking() bool {
+	return cfg.majorOnBreaking != nil && *cfg.majorOnBreaking
+}
+
+func (cfg *config) ShowVersionDiff() bool {
+	return cfg.showVersionDiff != nil && *cfg.showVersionDiff
+}
+
+func (cfg *config) NormalizeVersion() bool {
+	return cfg.normalizeVersion != nil && *cfg.normalizeVersion
+}
+
+func (cfg *config) PRComment() bool {
+	return cfg.prComment != nil && *cfg.prComment
+}
+
+func (cfg *config) DeleteBranchAfterMerge() bool {
+	return cfg.deleteBranch != nil && *cfg.deleteBranch
+}
+
+func (cfg *config) ShowClosedIssues() bool {
+	return cfg.showClosedIssues != nil && *cfg.showClosedIssues
+}
+
+func (cfg *config) TagReleaseOnly() bool {
+	return cfg.tagReleaseOnly != nil && *cfg.tagReleaseOnly
+}
+
+func (cfg *config) RunOnlyOnBranch() string {
+	if cfg.runOnlyOn == nil {
+		return ""
+	}
+	return cfg.runOnlyOn.String()
+}
+
+func (cfg *config) AdditionalRemotes() []string {
+	return cfg.addRemotes.List()
+}
+
+func (cfg *config) VersionCommand() string {
+	if cfg.verCommand == nil {
+		return ""
+	}
+	return cfg.verCommand.String()
+}
+
+func (cfg *config) Preflight() string {
+	if cfg.preflight == nil {
+		return ""
+	}
+	return cfg.preflight.String()
+}
+
+func (cfg *config) BodyCommand() string {
+	if cfg.bodyCommand == nil {
+		return ""
+	}
+	return cfg.bodyCommand.String()
+}
+
+func (cfg *config) NextDevSuffix() string {
+	if cfg.nextDevSuffix == nil {
+		return ""
+	}
+	return cfg.nextDevSuffix.String()
+}
+
+// VersionFileMissing returns how to handle missing version files, "error" or "skip"
+func (cfg *config) VersionFileMissing() string {
+	if cfg.vfMissing == nil || cfg.vfMissing.Empty() {
+		return versionFileMissingError
+	}
+	return cfg.vfMissing.String()
+}
+
+func (cfg *config) TitleBumpPattern() *configValue {
+	return cfg.titleBump
+}
+
+func (cfg *config) TagMessageFromPRBody() bool {
+	return cfg.tagMessageFromPRBody != nil && *cfg.tagMessageFromPRBody
+}
+
+func (cfg *config) AllowDirtyWorktree() bool {
+	return cfg.allowDirtyWorktree != nil && *cfg.allowDirtyWorktree
+}
+
+func (cfg *config) AmendReleaseCommit() bool {
+	return cfg.amendReleaseCommit != nil && *cfg.amendReleaseCommit
+}
+
+// AutoUnshallow returns true unless it is explicitly disabled
+func (cfg *config) AutoUnshallow() bool {
+	return cfg.autoUnshallow == nil || *cfg.autoUnshallow
+}
+
+func (cfg *config) IncludeCommandOutput() bool {
+	return cfg.includeComOutput != nil && *cfg.includeComOutput
+}
+
+func (cfg *config) IncludeDirectCommits() bool {
+	return cfg.includeDirectCommits != nil && *cfg.includeDirectCommits
+}
+
+func (cfg *config) WaitForChecks() bool {
+	return cfg.waitForChecks != nil && *cfg.waitForChecks
+}
+
+// ChecksTimeout returns the timeout to wait for the required checks. Defaults to 10 minutes.
+func (cfg *config) ChecksTimeout() (time.Duration, error) {
+	if cfg.checksTimeout == nil || cfg.checksTimeout.Empty() {
+		return defaultChecksTimeout, nil
+	}
+	d, err := time.ParseDuration(cfg.checksTimeout.String())
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s: %s", ErrInvalidConfig, configChecksTimeout, err)
+	}
+	return d, nil
+}
+
+func (cfg *config) TagExisting() string {
+	if cfg.tagExisting == nil {
+		return ""
+	}
+	return cfg.tagExisting.String()
+}
+
+func (cfg *config) FreezeCron() string {
+	if cfg.freezeCron == nil {
+		return ""
+	}
+	return cfg.freezeCron.String()
+}
+
+func (cfg *config) FreezeDates() []string {
+	return cfg.freezeDates.List()
+}
+
+func (cfg *config) RequireChecklist() bool {
+	return cfg.requireChecklist != nil && *cfg.requireChecklist
+}
+
+func (cfg *config) PRAutoCloseStale() bool {
+	return cfg.prAutoCloseStale != nil && *cfg.prAutoCloseStale
+}
+
+func (cfg *config) RequireNotes() bool {
+	return cfg.requireNotes != nil && *cfg.requireNotes
+}
+
+func (cfg *config) ChangelogLinkTemplate() string {
+	if cfg.changelogLink == nil {
+		return ""
+	}
+	return cfg.changelogLink.String()
+}
+
+func (cfg *config) Owner() string {
+	if cfg.owner == nil {
+		return ""
+	}
+	return cfg.owner.String()
+}
+
+func (cfg *config) Repo() string {
+	if cfg.repo == nil {
+		return ""
+	}
+	return cfg.repo.String()
+}
+
+func (cfg *config) Host() string {
+	if cfg.host == nil {
+		return ""
+	}
+	return cfg.host.String()
+}
+
+func (cfg *config) Milestone() string {
+	if cfg.milestone == nil {
+		return ""
+	}
+	return cfg.milestone.String()
+}
+
+func (cfg *config) AutoMerge() bool {
+	return cfg.autoMerge != nil && *cfg.autoMerge
+}
+
+// MergeMethod returns the merge method of the release pull request, "merge", "squash" or "rebase"
+func (cfg *config) MergeMethod() string {
+	if cfg.mergeMethod == nil {
+		return ""
+	}
+	return strings.ToLower(cfg.mergeMethod.String())
+}
+
+func (cfg *config) TagPrefix() string {
+	if cfg.tagPrefix == nil {
+		return ""
+	}
+	return cfg.tagPrefix.String()
+}
+
+func (cfg *config) PRBranchTemplate() string {
+	if cfg.prBranchTmpl == nil {
+		return ""
+	}
+	return cfg.prBranchTmpl.String()
+}
+
+func (cfg *config) TagTemplate() string {
+	if cfg.tagTemplate == nil {
+		return ""
+	}
+	return cfg.tagTemplate.String()
+}
+
+// TagFormat returns the format of tags from tagpr.tagTemplate or tagpr.tagPrefix
+func (cfg *config) TagFormat() (tagFormat, error) {
+	tmpl := cfg.TagTemplate()
+	if tmpl == "" {
+		return tagFormat{prefix: cfg.TagPrefix()}, nil
+	}
+	if cfg.TagPrefix() != "" {
+		return tagFormat{}, fmt.Errorf("%w: %s and %s can't be specified at the same time",
+			ErrInvalidConfig, configTagTemplate, configTagPrefix)
+	}
+	tf, err := newTagFormat(tmpl)
+	if err != nil {
+		return tagFormat{}, fmt.Errorf("%w: %s: %s", ErrInvalidConfig, configTagTemplate, err)
+	}
+	return tf, nil
+}
+
 type configValue struct {
 	value  string
 	source configSource
@@ -237,6 +1261,20 @@ func (cv *configValue) Empty() bool {
 	return cv.String() == ""
 }
 
+// List returns the comma separated values. It is safe to call with nil.
+func (cv *configValue) List() []string {
+	if cv == nil || cv.Empty() {
+		return nil
+	}
+	var list []string
+	for _, v := range strings.Split(cv.String(), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
 type configSource int
 
 const (
@@ -0,0 +1,230 @@
+package tagpr
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Songmu/gitconfig"
+)
+
+func TestGitconfigError(t *testing.T) {
+	newCfg := func(t *testing.T, content string) *config {
+		fpath := filepath.Join(t.TempDir(), ".tagpr")
+		if err := os.WriteFile(fpath, []byte(content), 0666); err != nil {
+			t.Fatal(err)
+		}
+		return &config{conf: fpath, gitPath: "git", gitconfig: &gitconfig.Config{GitPath: "git", File: fpath}}
+	}
+
+	t.Run("broken file", func(t *testing.T) {
+		cfg := newCfg(t, "[tagpr\n\treleaseBranch = main\n")
+		_, err := cfg.gitconfig.Get(configReleaseBranch)
+		if err == nil {
+			t.Fatal("error should be occurred")
+		}
+		msg := cfg.gitconfigError("read", configReleaseBranch, err, "--get").Error()
+		for _, s := range []string{"read", configReleaseBranch, cfg.conf, "bad config line 1"} {
+			if !strings.Contains(msg, s) {
+				t.Errorf("the error should contain %q: %s", s, msg)
+			}
+		}
+	})
+
+	t.Run("invalid boolean", func(t *testing.T) {
+		cfg := newCfg(t, "[tagpr]\n\tautoMerge = maybe\n")
+		_, err := cfg.gitconfig.Bool(configAutoMerge)
+		if err == nil {
+			t.Fatal("error should be occurred")
+		}
+		msg := cfg.gitconfigError("read", configAutoMerge, err, "--get", "--bool").Error()
+		if !strings.Contains(msg, "bad boolean config value") {
+			t.Errorf("the error should contain the stderr of git: %s", msg)
+		}
+	})
+}
+
+func TestGetBool(t *testing.T) {
+	fpath := filepath.Join(t.TempDir(), ".tagpr")
+	if err := os.WriteFile(fpath, []byte("[tagpr]\n\tvPrefix = false\n\tautoMerge = true\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config{conf: fpath, gitPath: "git", gitconfig: &gitconfig.Config{GitPath: "git", File: fpath}}
+	f, tr := false, true
+	testCases := []struct {
+		name, env, confKey string
+		expect             *bool
+	}{
+		{"unset", "", configMilestone, nil},
+		{"false in file", "", configVPrefix, &f},
+		{"true in file", "", configAutoMerge, &tr},
+		{"explicit false by env", "false", configMilestone, &f},
+		{"env overrides file", "false", configAutoMerge, &f},
+		{"off by env", "off", configMilestone, &f},
+		{"yes by env", "yes", configVPrefix, &tr},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("TAGPR_TEST_BOOL", tc.env)
+			got, err := cfg.getBool("TAGPR_TEST_BOOL", tc.confKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (got == nil) != (tc.expect == nil) || got != nil && *got != *tc.expect {
+				t.Errorf("got: %v, expected: %v", got, tc.expect)
+			}
+		})
+	}
+
+	t.Setenv("TAGPR_TEST_BOOL", "maybe")
+	if _, err := cfg.getBool("TAGPR_TEST_BOOL", configVPrefix); err == nil {
+		t.Error("error should be occurred")
+	}
+}
+
+func TestGitPath(t *testing.T) {
+	dir := t.TempDir()
+	shim := func(name string) (string, string) {
+		logFile := filepath.Join(dir, name+".log")
+		fpath := filepath.Join(dir, name)
+		script := "#!/bin/sh\necho \"$@\" >> " + logFile + "\nexec git \"$@\"\n"
+		if err := os.WriteFile(fpath, []byte(script), 0755); err != nil {
+			t.Fatal(err)
+		}
+		return fpath, logFile
+	}
+	readLog := func(logFile string) string {
+		bs, err := os.ReadFile(logFile)
+		if err != nil && !os.IsNotExist(err) {
+			t.Fatal(err)
+		}
+		return string(bs)
+	}
+	confShim, confLog := shim("conf-git")
+	envShim, envLog := shim("env-git")
+	newTestRepo(t, "[tagpr]\n\treleaseBranch = main\n\tversionFile = -\n\tgitPath = "+confShim+"\n")
+	newTP := func() *tagpr {
+		tp, err := newTagPR(context.Background(), &commander{
+			gitPath: "git", outStream: io.Discard, errStream: io.Discard, dir: "."}, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tp
+	}
+
+	// tagpr.gitPath is used after reading the config file by the default git
+	tp := newTP()
+	if tp.gitPath != confShim || tp.c.gitPath != confShim {
+		t.Errorf("got: %s, expect: %s", tp.gitPath, confShim)
+	}
+	log := readLog(confLog)
+	if !strings.Contains(log, "ls-remote") {
+		t.Errorf("the git operations after reading the config file should be done by tagpr.gitPath: %s", log)
+	}
+	if strings.Contains(log, "--file .tagpr --get") {
+		t.Errorf("the config file should be read by the default git: %s", log)
+	}
+	if err := tp.cfg.set(configReleaseBranch, "main"); err != nil {
+		t.Fatal(err)
+	}
+	if log := readLog(confLog); !strings.Contains(log, "tagpr.releaseBranch main") {
+		t.Errorf("the config file should be written by tagpr.gitPath: %s", log)
+	}
+
+	// TAGPR_GIT_PATH takes precedence over tagpr.gitPath, including reading the config file
+	os.Remove(confLog)
+	t.Setenv(envGitPath, envShim)
+	tp = newTP()
+	if tp.gitPath != envShim || tp.c.gitPath != envShim {
+		t.Errorf("got: %s, expect: %s", tp.gitPath, envShim)
+	}
+	log = readLog(envLog)
+	for _, s := range []string{"--file .tagpr", "ls-remote"} {
+		if !strings.Contains(log, s) {
+			t.Errorf("%s should be called with %q: %s", envGitPath, s, log)
+		}
+	}
+	if log := readLog(confLog); log != "" {
+		t.Errorf("tagpr.gitPath should not be used with %s: %s", envGitPath, log)
+	}
+}
+
+func TestConfigPrecedence(t *testing.T) {
+	newCfg := func(t *testing.T, content string) *config {
+		fpath := filepath.Join(t.TempDir(), ".tagpr")
+		if err := os.WriteFile(fpath, []byte(content), 0666); err != nil {
+			t.Fatal(err)
+		}
+		return &config{conf: fpath, gitPath: "git", gitconfig: &gitconfig.Config{GitPath: "git", File: fpath}}
+	}
+	testCases := []struct {
+		name, precedence, envPrecedence string
+		expectBranch                    string
+		expectVPrefix                   bool
+	}{
+		{"default", "", "", "develop", true},
+		{"env", "env", "", "develop", true},
+		{"file", "file", "", "main", false},
+		{"file by env", "", "file", "main", false},
+		{"file beats env", "file", "env", "main", false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			content := "[tagpr]\n\treleaseBranch = main\n\tvPrefix = false\n"
+			if tc.precedence != "" {
+				content += "\tconfigPrecedence = " + tc.precedence + "\n"
+			}
+			cfg := newCfg(t, content)
+			t.Setenv(envConfigPrecedence, tc.envPrecedence)
+			t.Setenv(envReleaseBranch, "develop")
+			t.Setenv(envVPrefix, "true")
+			t.Setenv(envTagPrefix, "app/")
+			if err := cfg.Reload(); err != nil {
+				t.Fatal(err)
+			}
+			if g := cfg.ReleaseBranch().String(); g != tc.expectBranch {
+				t.Errorf("releaseBranch: got: %s, expect: %s", g, tc.expectBranch)
+			}
+			if g := *cfg.vPrefix; g != tc.expectVPrefix {
+				t.Errorf("vPrefix: got: %t, expect: %t", g, tc.expectVPrefix)
+			}
+			// the environment variables are still used for the settings not in the file
+			if g := cfg.TagPrefix(); g != "app/" {
+				t.Errorf("tagPrefix: got: %s, expect: app/", g)
+			}
+		})
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		cfg := newCfg(t, "[tagpr]\n\tconfigPrecedence = both\n")
+		if err := cfg.Reload(); !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("ErrInvalidConfig should be returned: %v", err)
+		}
+	})
+}
+
+func TestUserAgent(t *testing.T) {
+	fpath := filepath.Join(t.TempDir(), ".tagpr")
+	if err := os.WriteFile(fpath, []byte("[tagpr]\n\treleaseBranch = main\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config{conf: fpath, gitPath: "git", gitconfig: &gitconfig.Config{GitPath: "git", File: fpath}}
+	t.Setenv(envUserAgent, "")
+	if err := cfg.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if expect := "tagpr/" + version + " (+https://github.com/Songmu/tagpr)"; cfg.UserAgent() != expect {
+		t.Errorf("got: %q, expected: %q", cfg.UserAgent(), expect)
+	}
+	t.Setenv(envUserAgent, "tagpr/custom")
+	if err := cfg.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.UserAgent(); got != "tagpr/custom" {
+		t.Errorf("got: %q, expected: %q", got, "tagpr/custom")
+	}
+}
@@ -0,0 +1,114 @@
+package tagpr
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Dump serializes the effective configuration, including the values from the environment
+// variables, in the format of the .tagpr file. The unset values are omitted and the null
+// value "-" is kept as is, so newConfig reads the same configuration from the output.
+// tagpr.tagExisting is omitted as it is a one-off operation.
+func (cfg *config) Dump() string {
+	section := "[tagpr]"
+	if cfg.profile != "" {
+		section = fmt.Sprintf("[tagpr %s]", quoteConfigValue(cfg.profile, true))
+	}
+	b := &strings.Builder{}
+	fmt.Fprintln(b, section)
+	if cfg.preferFile {
+		dumpValue(b, configConfigPrecedence, precedenceFile)
+	}
+	for _, v := range []struct {
+		key string
+		cv  *configValue
+	}{
+		{configReleaseBranch, cfg.releaseBranch},
+		{configVersionFile, cfg.versionFile},
+		{configCommand, cfg.command},
+		{configTemplate, cfg.template},
+		{configTagPrefix, cfg.tagPrefix},
+		{configTitleBumpPattern, cfg.titleBump},
+		{configVersionFileMissing, cfg.vfMissing},
+		{configPRBaseBranch, cfg.prBaseBranch},
+		{configNoReleaseLabels, cfg.noRelLabels},
+		{configPRLabelsRequiredToTag, cfg.reqLabels},
+		{configNextDevSuffix, cfg.nextDevSuffix},
+		{configCommandTimeout, cfg.comTimeout},
+		{configMergeMethod, cfg.mergeMethod},
+		{configMilestone, cfg.milestone},
+		{configTagTemplate, cfg.tagTemplate},
+		{configOwner, cfg.owner},
+		{configRepo, cfg.repo},
+		{configHost, cfg.host},
+		{configChangelogLinkTemplate, cfg.changelogLink},
+		{configLabelPrefixes, cfg.labelPrefixes},
+		{configBodyCommand, cfg.bodyCommand},
+		{configVersionCommand, cfg.verCommand},
+		{configPreflight, cfg.preflight},
+		{configAdditionalRemotes, cfg.addRemotes},
+		{configRunOnlyOnBranch, cfg.runOnlyOn},
+		{configChartKeys, cfg.chartKeys},
+		{configChecksumsFile, cfg.checksums},
+		{configReleaseAssets, cfg.relAssets},
+		{configChecksTimeout, cfg.checksTimeout},
+		{configFreezeCron, cfg.freezeCron},
+		{configFreezeDates, cfg.freezeDates},
+		{configBreakingLabels, cfg.breakLabels},
+		{configPRTemplateEngine, cfg.tmplEngine},
+		{configGitPath, cfg.gitBin},
+		{configHighlightReactions, cfg.highlights},
+		{configRecentReleasesCount, cfg.recentRels},
+		{configNotesFormat, cfg.notesFmt},
+		{configNotesSort, cfg.notesSort},
+		{configPRBranchTemplate, cfg.prBranchTmpl},
+		{configUserAgent, cfg.userAgent},
+	} {
+		if v.cv != nil {
+			dumpValue(b, v.key, v.cv.value)
+		}
+	}
+	for _, v := range []struct {
+		key string
+		b   *bool
+	}{
+		{configVPrefix, cfg.vPrefix},
+		{configTagMessageFromPRBody, cfg.tagMessageFromPRBody},
+		{configAllowDirtyWorktree, cfg.allowDirtyWorktree},
+		{configAmendReleaseCommit, cfg.amendReleaseCommit},
+		{configAutoUnshallow, cfg.autoUnshallow},
+		{configIncludeCommandOutput, cfg.includeComOutput},
+		{configAutoMerge, cfg.autoMerge},
+		{configIncludeDirectCommits, cfg.includeDirectCommits},
+		{configRequireNotes, cfg.requireNotes},
+		{configPRAutoCloseStale, cfg.prAutoCloseStale},
+		{configRequireChecklist, cfg.requireChecklist},
+		{configWaitForChecks, cfg.waitForChecks},
+		{configMajorOnBreaking, cfg.majorOnBreaking},
+		{configShowVersionDiff, cfg.showVersionDiff},
+		{configNormalizeVersion, cfg.normalizeVersion},
+		{configPRComment, cfg.prComment},
+		{configDeleteBranchAfterMerge, cfg.deleteBranch},
+		{configShowClosedIssues, cfg.showClosedIssues},
+		{configTagReleaseOnly, cfg.tagReleaseOnly},
+	} {
+		if v.b != nil {
+			dumpValue(b, v.key, fmt.Sprint(*v.b))
+		}
+	}
+	return b.String()
+}
+
+func dumpValue(b *strings.Builder, key, value string) {
+	fmt.Fprintf(b, "\t%s = %s\n", strings.TrimPrefix(key, "tagpr."), quoteConfigValue(value, false))
+}
+
+// quoteConfigValue quotes the value in the git config format if needed.
+// The subsection names are always quoted.
+func quoteConfigValue(v string, force bool) string {
+	if !force && v != "" && v == strings.TrimSpace(v) && !strings.ContainsAny(v, "#;\"\\\n\t") {
+		return v
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`)
+	return `"` + r.Replace(v) + `"`
+}
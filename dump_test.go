@@ -0,0 +1,83 @@
+package tagpr
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Songmu/gitconfig"
+)
+
+func TestDump(t *testing.T) {
+	load := func(t *testing.T, content, profile string) *config {
+		fpath := filepath.Join(t.TempDir(), ".tagpr")
+		if err := os.WriteFile(fpath, []byte(content), 0666); err != nil {
+			t.Fatal(err)
+		}
+		cfg := &config{conf: fpath, profile: profile, gitPath: "git",
+			gitconfig: &gitconfig.Config{GitPath: "git", File: fpath}}
+		if err := cfg.Reload(); err != nil {
+			t.Fatal(err)
+		}
+		return cfg
+	}
+	content := `[tagpr]
+	releaseBranch = main
+	versionFile = -
+	titleBumpPattern = "^\\[(major|minor|patch)\\]"
+	changelogLinkTemplate = "https://example.com/#{{.Tag}}"
+	vPrefix = true
+	autoMerge = false
+[tagpr "backend"]
+	tagPrefix = backend/
+`
+	t.Setenv(envCommand, "make  bump")
+	cfg := load(t, content, "backend")
+	out := cfg.Dump()
+	for _, s := range []string{
+		`[tagpr "backend"]`,
+		"\treleaseBranch = main\n",
+		"\tversionFile = -\n",
+		"\ttagPrefix = backend/\n",
+		"\tcommand = make  bump\n",
+		`titleBumpPattern = "^\\[(major|minor|patch)\\]"`,
+		"\tvPrefix = true\n",
+		"\tautoMerge = false\n",
+	} {
+		if !strings.Contains(out, s) {
+			t.Errorf("the dump should contain %q:\n%s", s, out)
+		}
+	}
+
+	t.Setenv(envCommand, "")
+	cfg2 := load(t, out, "backend")
+	if out2 := cfg2.Dump(); out2 != out {
+		t.Errorf("round trip failed:\n%s\nexpect:\n%s", out2, out)
+	}
+	if g, e := cfg2.TitleBumpPattern().String(), cfg.TitleBumpPattern().String(); g != e {
+		t.Errorf("got: %s, expect: %s", g, e)
+	}
+	if g := cfg2.VersionFile().String(); g != "" {
+		t.Errorf("versionFile should be null: %s", g)
+	}
+}
+
+func TestQuoteConfigValue(t *testing.T) {
+	testCases := []struct {
+		in, expect string
+	}{
+		{"main", "main"},
+		{"-", "-"},
+		{"", `""`},
+		{" padded", `" padded"`},
+		{"a # b", `"a # b"`},
+		{`say "hi"`, `"say \"hi\""`},
+		{"a\nb", `"a\nb"`},
+	}
+	for _, tc := range testCases {
+		if g := quoteConfigValue(tc.in, false); g != tc.expect {
+			t.Errorf("quoteConfigValue(%q) = %s, expect: %s", tc.in, g, tc.expect)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package tagpr
+
+import "errors"
+
+var (
+	// ErrNoReleaseBranch is returned when the current branch is not the release branch
+	ErrNoReleaseBranch = errors.New("not on the release branch")
+	// ErrNoChanges is returned when there is nothing to release
+	ErrNoChanges = errors.New("nothing to release")
+	// ErrInvalidVersionFile is returned when the version file is missing or has no version
+	ErrInvalidVersionFile = errors.New("invalid version file")
+	// ErrDirtyWorktree is returned when the working tree has uncommitted changes
+	ErrDirtyWorktree = errors.New("dirty working tree")
+	// ErrInvalidConfig is returned when the configuration has an invalid value
+	ErrInvalidConfig = errors.New("invalid config")
+	// ErrTagMismatch is returned when the pushed tag on the remote doesn't point at the expected commit
+	ErrTagMismatch = errors.New("tag mismatch")
+	// ErrEmptyNotes is returned when the release notes are empty though they are required
+	ErrEmptyNotes = errors.New("empty release notes")
+	// ErrVetoed is returned when tagpr.preflight vetoes the release
+	ErrVetoed = errors.New("release vetoed")
+	// ErrVersionRegression is returned when the next version is not greater than the latest existing one
+	ErrVersionRegression = errors.New("version regression")
+)
@@ -0,0 +1,119 @@
+package tagpr
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// fileTxn writes multiple files all or nothing. The contents are staged first, and
+// written to the temporary files and renamed into place on commit. If any of them fail,
+// the files already renamed are restored to the original contents.
+type fileTxn struct {
+	paths    []string
+	contents map[string][]byte
+	// normalize is tagpr.normalizeVersion
+	normalize bool
+}
+
+func newFileTxn(normalize bool) *fileTxn {
+	return &fileTxn{contents: map[string][]byte{}, normalize: normalize}
+}
+
+// stage stages the content of the file. The later one wins for the same file.
+func (txn *fileTxn) stage(fpath string, content []byte) {
+	if _, ok := txn.contents[fpath]; !ok {
+		txn.paths = append(txn.paths, fpath)
+	}
+	txn.contents[fpath] = content
+}
+
+// read returns the staged content of the file if any, otherwise the content on the disk,
+// so that the multiple edits of the same file are accumulated.
+func (txn *fileTxn) read(fpath string) ([]byte, error) {
+	if content, ok := txn.contents[fpath]; ok {
+		return content, nil
+	}
+	return os.ReadFile(fpath)
+}
+
+// bumpVersionFile stages the bump of the version file. See bumpVersionFile function.
+func (txn *fileTxn) bumpVersionFile(spec string, from, to *semv) (bool, error) {
+	fpath, updated, err := bumpedVersionFile(txn.read, spec, from, to, txn.normalize)
+	if err != nil || updated == nil {
+		return false, err
+	}
+	txn.stage(fpath, updated)
+	return true, nil
+}
+
+// bumpChartYAML stages the bump of the Chart.yaml. See bumpChartYAML function.
+func (txn *fileTxn) bumpChartYAML(fpath string, keys []string, to *semv) (bool, error) {
+	updated, err := bumpedChartYAML(txn.read, fpath, keys, to)
+	if err != nil || updated == nil {
+		return false, err
+	}
+	txn.stage(fpath, updated)
+	return true, nil
+}
+
+func (txn *fileTxn) commit() error {
+	temps := map[string]string{}
+	defer func() {
+		for _, tmp := range temps {
+			os.Remove(tmp)
+		}
+	}()
+	origs := map[string][]byte{}
+	modes := map[string]os.FileMode{}
+	for _, fpath := range txn.paths {
+		fi, err := os.Stat(fpath)
+		if err != nil {
+			return err
+		}
+		if origs[fpath], err = os.ReadFile(fpath); err != nil {
+			return err
+		}
+		modes[fpath] = fi.Mode().Perm()
+		tmp, err := writeTemp(fpath, txn.contents[fpath], modes[fpath])
+		if err != nil {
+			return err
+		}
+		temps[fpath] = tmp
+	}
+	for i, fpath := range txn.paths {
+		if err := os.Rename(temps[fpath], fpath); err != nil {
+			for _, done := range txn.paths[:i] {
+				if rerr := os.WriteFile(done, origs[done], modes[done]); rerr != nil {
+					err = fmt.Errorf("%w (and failed to restore %s: %s)", err, done, rerr)
+				}
+			}
+			return err
+		}
+		delete(temps, fpath)
+	}
+	return nil
+}
+
+// writeTemp writes the content to the temporary file in the same directory as the file,
+// to rename it into place atomically.
+func writeTemp(fpath string, content []byte, mode os.FileMode) (string, error) {
+	f, err := os.CreateTemp(filepath.Dir(fpath), "."+filepath.Base(fpath)+".tagpr-*")
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.Write(content); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	if err := os.Chmod(f.Name(), mode); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
@@ -0,0 +1,63 @@
+package tagpr
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileTxn(t *testing.T) {
+	dir := t.TempDir()
+	pkg := filepath.Join(dir, "package.json")
+	ver := filepath.Join(dir, "VERSION")
+	if err := os.WriteFile(pkg, []byte(`{"version": "1.2.3", "peer": {"version": "1.2.3"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(ver, []byte("1.2.3\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	from, _ := newSemver("1.2.3")
+	to, _ := newSemver("1.3.0")
+
+	t.Run("rollback", func(t *testing.T) {
+		txn := newFileTxn(false)
+		if _, err := txn.bumpVersionFile(ver, from, to); err != nil {
+			t.Fatal(err)
+		}
+		txn.stage(filepath.Join(dir, "missing", "VERSION"), []byte("1.3.0\n"))
+		if err := txn.commit(); err == nil {
+			t.Fatal("error should be occurred")
+		}
+		if bs, _ := os.ReadFile(ver); string(bs) != "1.2.3\n" {
+			t.Errorf("the file should not be changed, but got: %q", bs)
+		}
+		if matches, _ := filepath.Glob(filepath.Join(dir, ".*.tagpr-*")); len(matches) > 0 {
+			t.Errorf("the temporary files should be removed, but got: %v", matches)
+		}
+	})
+
+	t.Run("commit", func(t *testing.T) {
+		txn := newFileTxn(false)
+		for _, spec := range []string{pkg + "#/version", pkg + "#/peer/version", ver} {
+			replaced, err := txn.bumpVersionFile(spec, from, to)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !replaced {
+				t.Errorf("%s should be replaced", spec)
+			}
+		}
+		if err := txn.commit(); err != nil {
+			t.Fatal(err)
+		}
+		if bs, _ := os.ReadFile(pkg); string(bs) != `{"version": "1.3.0", "peer": {"version": "1.3.0"}}` {
+			t.Errorf("unexpected content: %q", bs)
+		}
+		if bs, _ := os.ReadFile(ver); string(bs) != "1.3.0\n" {
+			t.Errorf("unexpected content: %q", bs)
+		}
+		if fi, _ := os.Stat(ver); fi.Mode().Perm() != 0600 {
+			t.Errorf("the file mode should be preserved, but got: %s", fi.Mode())
+		}
+	})
+}
@@ -0,0 +1,149 @@
+package tagpr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSchedule is the schedule in the five fields of cron: minute, hour, day of month, month
+// and day of week. Each field holds the matching values as the set.
+type cronSchedule struct {
+	expr   string
+	fields [5]map[int]bool
+	// Same as cron, if both the day of month and the day of week are restricted,
+	// the time matches either of them.
+	domStar, dowStar bool
+}
+
+var cronBounds = [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+
+// parseCron parses the cron expression like "* * * * 0,6". Each field accepts "*", the number,
+// the range "a-b", the step "*/n" or "a-b/n", and the lists of them separated by commas.
+// The day of week is 0-7 where both 0 and 7 are Sunday.
+func parseCron(expr string) (*cronSchedule, error) {
+	fs := strings.Fields(expr)
+	if len(fs) != 5 {
+		return nil, fmt.Errorf("the cron expression must have 5 fields: %q", expr)
+	}
+	cs := &cronSchedule{expr: expr, domStar: fs[2] == "*", dowStar: fs[4] == "*"}
+	for i, f := range fs {
+		set, err := parseCronField(f, cronBounds[i][0], cronBounds[i][1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron field %q in %q: %w", f, expr, err)
+		}
+		cs.fields[i] = set
+	}
+	if cs.fields[4][7] {
+		cs.fields[4][0] = true
+	}
+	return cs, nil
+}
+
+func parseCronField(f string, min, max int) (map[int]bool, error) {
+	set := map[int]bool{}
+	for _, part := range strings.Split(f, ",") {
+		rng, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step < 1 {
+				return nil, fmt.Errorf("invalid step %q", part[i+1:])
+			}
+			rng = part[:i]
+		}
+		lo, hi := min, max
+		if rng != "*" {
+			var err error
+			bounds := strings.SplitN(rng, "-", 2)
+			if lo, err = strconv.Atoi(bounds[0]); err != nil {
+				return nil, fmt.Errorf("invalid value %q", bounds[0])
+			}
+			hi = lo
+			if len(bounds) == 2 {
+				if hi, err = strconv.Atoi(bounds[1]); err != nil {
+					return nil, fmt.Errorf("invalid value %q", bounds[1])
+				}
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return nil, fmt.Errorf("out of range %q: %d-%d", rng, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			set[v] = true
+		}
+	}
+	return set, nil
+}
+
+func (cs *cronSchedule) matches(t time.Time) bool {
+	if !cs.fields[0][t.Minute()] || !cs.fields[1][t.Hour()] || !cs.fields[3][int(t.Month())] {
+		return false
+	}
+	dom, dow := cs.fields[2][t.Day()], cs.fields[4][int(t.Weekday())]
+	switch {
+	case cs.domStar && cs.dowStar:
+		return true
+	case cs.domStar:
+		return dow
+	case cs.dowStar:
+		return dom
+	}
+	return dom || dow
+}
+
+// dateRange is the range of the dates including the both ends.
+type dateRange struct {
+	from, to time.Time
+}
+
+const freezeDateLayout = "2006-01-02"
+
+// parseDateRange parses the range of the dates like "2026-12-24..2027-01-03" or the single
+// date like "2026-12-31". The dates are in the time zone of loc.
+func parseDateRange(s string, loc *time.Location) (dateRange, error) {
+	from, to, ok := strings.Cut(s, "..")
+	if !ok {
+		to = from
+	}
+	f, err := time.ParseInLocation(freezeDateLayout, strings.TrimSpace(from), loc)
+	if err != nil {
+		return dateRange{}, err
+	}
+	t, err := time.ParseInLocation(freezeDateLayout, strings.TrimSpace(to), loc)
+	if err != nil {
+		return dateRange{}, err
+	}
+	if t.Before(f) {
+		return dateRange{}, fmt.Errorf("the end is before the start: %q", s)
+	}
+	return dateRange{from: f, to: t.AddDate(0, 0, 1)}, nil
+}
+
+func (dr dateRange) contains(t time.Time) bool {
+	return !t.Before(dr.from) && t.Before(dr.to)
+}
+
+// frozen reports the freeze window of tagpr.freezeCron and tagpr.freezeDates containing
+// the time, or an empty string if the time is out of them.
+func (cfg *config) frozen(now time.Time) (string, error) {
+	if expr := cfg.FreezeCron(); expr != "" {
+		cs, err := parseCron(expr)
+		if err != nil {
+			return "", fmt.Errorf("%w: %s: %s", ErrInvalidConfig, configFreezeCron, err)
+		}
+		if cs.matches(now) {
+			return fmt.Sprintf("%s %q", configFreezeCron, expr), nil
+		}
+	}
+	for _, d := range cfg.FreezeDates() {
+		dr, err := parseDateRange(d, now.Location())
+		if err != nil {
+			return "", fmt.Errorf("%w: %s: %s", ErrInvalidConfig, configFreezeDates, err)
+		}
+		if dr.contains(now) {
+			return fmt.Sprintf("%s %q", configFreezeDates, d), nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,69 @@
+package tagpr
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronSchedule(t *testing.T) {
+	testCases := []struct {
+		expr   string
+		time   string
+		expect bool
+	}{
+		{"* * * * 0,6", "2026-10-17T10:00:00Z", true}, // Saturday
+		{"* * * * 0,6", "2026-10-18T10:00:00Z", true}, // Sunday
+		{"* * * * 0,6", "2026-10-19T10:00:00Z", false},
+		{"* * * * 6-7", "2026-10-18T10:00:00Z", true},
+		{"* 18-23 * * 5", "2026-10-16T18:30:00Z", true},
+		{"* 18-23 * * 5", "2026-10-16T17:59:00Z", false},
+		{"*/15 * * * *", "2026-10-16T17:45:00Z", true},
+		{"*/15 * * * *", "2026-10-16T17:46:00Z", false},
+		{"* * 24-31 12 *", "2026-12-25T00:00:00Z", true},
+		{"* * 24-31 12 *", "2026-11-25T00:00:00Z", false},
+		// either of the day of month or the day of week matches
+		{"* * 1 * 1", "2026-10-01T00:00:00Z", true},
+		{"* * 1 * 1", "2026-10-19T00:00:00Z", true},
+		{"* * 1 * 1", "2026-10-20T00:00:00Z", false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.expr+" "+tc.time, func(t *testing.T) {
+			cs, err := parseCron(tc.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			now, _ := time.Parse(time.RFC3339, tc.time)
+			if got := cs.matches(now); got != tc.expect {
+				t.Errorf("got: %t, expect: %t", got, tc.expect)
+			}
+		})
+	}
+
+	for _, expr := range []string{"* * * *", "60 * * * *", "* * * * 8", "* * * * 5-1", "*/0 * * * *", "a * * * *"} {
+		if _, err := parseCron(expr); err == nil {
+			t.Errorf("error should be occurred: %q", expr)
+		}
+	}
+}
+
+func TestDateRange(t *testing.T) {
+	dr, err := parseDateRange("2026-12-24..2027-01-03", time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for tm, expect := range map[string]bool{
+		"2026-12-23T23:59:59Z": false,
+		"2026-12-24T00:00:00Z": true,
+		"2027-01-03T23:59:59Z": true,
+		"2027-01-04T00:00:00Z": false,
+	} {
+		now, _ := time.Parse(time.RFC3339, tm)
+		if got := dr.contains(now); got != expect {
+			t.Errorf("%s: got: %t, expect: %t", tm, got, expect)
+		}
+	}
+
+	if _, err := parseDateRange("2027-01-03..2026-12-24", time.UTC); err == nil {
+		t.Error("error should be occurred")
+	}
+}
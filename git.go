@@ -2,9 +2,12 @@ package tagpr
 
 import (
 	"bytes"
+	"context"
+	"fmt"
 	"io"
 	"log"
 	"os/exec"
+	"path/filepath"
 	"strings"
 )
 
@@ -21,15 +24,26 @@ func (c *commander) getGitPath() string {
 }
 
 func (c *commander) Cmd(prog string, args ...string) (string, string, error) {
+	return c.CmdContext(context.Background(), prog, args...)
+}
+
+// CmdContext runs the command with the context, and the command is killed when the context is done.
+func (c *commander) CmdContext(ctx context.Context, prog string, args ...string) (string, string, error) {
+	return c.CmdWithInput(ctx, nil, prog, args...)
+}
+
+// CmdWithInput runs the command with the context as CmdContext, and passes the input to its stdin.
+func (c *commander) CmdWithInput(ctx context.Context, input io.Reader, prog string, args ...string) (string, string, error) {
 	log.Println(prog, args)
 
 	var (
 		outBuf bytes.Buffer
 		errBuf bytes.Buffer
 	)
-	cmd := exec.Command(prog, args...)
+	cmd := exec.CommandContext(ctx, prog, args...)
 	cmd.Stdout = io.MultiWriter(&outBuf, c.outStream)
 	cmd.Stderr = io.MultiWriter(&errBuf, c.errStream)
+	cmd.Stdin = input
 	if c.dir != "" {
 		cmd.Dir = c.dir
 	}
@@ -37,6 +51,25 @@ func (c *commander) Cmd(prog string, args ...string) (string, string, error) {
 	return strings.TrimSpace(outBuf.String()), strings.TrimSpace(errBuf.String()), err
 }
 
+// findRepoRoot finds the root of the git repository by walking up from the directory
+// for the ".git" directory, or the ".git" file for the worktrees and the submodules.
+func findRepoRoot(dir string) (string, error) {
+	d, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		if exists(filepath.Join(d, ".git")) {
+			return d, nil
+		}
+		parent := filepath.Dir(d)
+		if parent == d {
+			return "", fmt.Errorf("not in a git repository: %s", dir)
+		}
+		d = parent
+	}
+}
+
 func (c *commander) Git(args ...string) (string, string, error) {
 	return c.Cmd(c.getGitPath(), args...)
 }
@@ -0,0 +1,35 @@
+package tagpr
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFindRepoRoot(t *testing.T) {
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	nested := filepath.Join(root, "a", "b")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := findRepoRoot(nested); err == nil {
+		t.Error("error should be occurred outside of the repository")
+	}
+
+	// the ".git" file of the worktree
+	if err := os.WriteFile(filepath.Join(root, ".git"), []byte("gitdir: /path/to/main/.git/worktrees/x\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, dir := range []string{root, nested} {
+		got, err := findRepoRoot(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != root {
+			t.Errorf("got: %s, expect: %s", got, root)
+		}
+	}
+}
@@ -2,34 +2,124 @@ package tagpr
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
 	"net/url"
+	"os/exec"
+	"strings"
 
 	"github.com/Songmu/gitconfig"
+	"github.com/cli/go-gh/pkg/auth"
 	"github.com/google/go-github/v47/github"
 	"golang.org/x/oauth2"
 )
 
-func ghClient(ctx context.Context, token, host string) (*github.Client, error) {
+func ghClient(ctx context.Context, token, host, userAgent string) (*github.Client, error) {
 	if token == "" {
-		var err error
-		token, err = gitconfig.GitHubToken(host)
+		var (
+			source string
+			err    error
+		)
+		token, source, err = githubToken(ctx, host)
 		if err != nil {
 			return nil, err
 		}
+		log.Printf("the GitHub token is taken from %s\n", source)
 	}
 	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
 	oauthClient := oauth2.NewClient(ctx, ts)
 	client := github.NewClient(oauthClient)
+	if userAgent != "" {
+		client.UserAgent = userAgent
+	}
 
 	if host != "" && host != "github.com" {
 		// ref. https://github.com/google/go-github/issues/958
-		host = fmt.Sprintf("https://%s/api/v3/", host)
-		u, err := url.Parse(host)
+		u, err := url.Parse(fmt.Sprintf("https://%s/api/v3/", host))
 		if err != nil {
 			return nil, err
 		}
 		client.BaseURL = u
+		if client.UploadURL, err = url.Parse(fmt.Sprintf("https://%s/api/uploads/", host)); err != nil {
+			return nil, err
+		}
 	}
 	return client, nil
 }
+
+// githubToken finds the token for the host by gitconfig.GitHubToken as before, which looks up
+// the environment variables (GH_TOKEN, GITHUB_TOKEN and the ones for GitHub Enterprise), the
+// config file of the gh CLI, the "github.token" of git config and the config of hub in this order.
+// It falls back to "gh auth token" at last, for the token of the gh CLI stored in the keyring
+// on the local runs. It returns the source of the token as well.
+func githubToken(ctx context.Context, host string) (token, source string, err error) {
+	if host == "" {
+		host = "github.com"
+	}
+	token, err = gitconfig.GitHubToken(host)
+	if err == nil {
+		source = "git config github.token or the config of hub"
+		if t, src := auth.TokenForHost(host); t == token {
+			source = src
+			if source == "oauth_token" {
+				source = "the config of the gh CLI"
+			}
+		}
+		return token, source, nil
+	}
+	out, ghErr := exec.CommandContext(ctx, "gh", "auth", "token", "--hostname", host).Output()
+	if ghErr != nil {
+		if !errors.Is(ghErr, exec.ErrNotFound) {
+			log.Printf("failed to retrieve the token by gh auth token: %s\n", ghErr)
+		}
+		return "", "", err
+	}
+	if token = strings.TrimSpace(string(out)); token == "" {
+		return "", "", err
+	}
+	return token, "gh auth token", nil
+}
+
+const (
+	mergeMethodMerge  = "merge"
+	mergeMethodSquash = "squash"
+	mergeMethodRebase = "rebase"
+)
+
+const enableAutoMergeMutation = `mutation($id: ID!, $method: PullRequestMergeMethod!) {
+  enablePullRequestAutoMerge(input: {pullRequestId: $id, mergeMethod: $method}) {
+    clientMutationId
+  }
+}`
+
+// enableAutoMerge enables the auto-merge of the pull request by the GraphQL API,
+// because the REST API doesn't support it.
+func enableAutoMerge(ctx context.Context, cli *github.Client, host, nodeID, mergeMethod string) error {
+	endpoint := "https://api.github.com/graphql"
+	if host != "" && host != "github.com" {
+		endpoint = fmt.Sprintf("https://%s/api/graphql", host)
+	}
+	req, err := cli.NewRequest("POST", endpoint, map[string]interface{}{
+		"query": enableAutoMergeMutation,
+		"variables": map[string]string{
+			"id":     nodeID,
+			"method": strings.ToUpper(mergeMethod),
+		},
+	})
+	if err != nil {
+		return err
+	}
+	var res struct {
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if _, err := cli.Do(ctx, req, &res); err != nil {
+		return err
+	}
+	if len(res.Errors) > 0 {
+		return fmt.Errorf("failed to enable auto-merge: %s", res.Errors[0].Message)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package tagpr
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGitHubToken_ghAuthToken(t *testing.T) {
+	dir := t.TempDir()
+	// isolate the other sources of the token
+	for _, k := range []string{"GH_TOKEN", "GITHUB_TOKEN", "GH_ENTERPRISE_TOKEN", "GITHUB_ENTERPRISE_TOKEN"} {
+		t.Setenv(k, "")
+	}
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("GH_CONFIG_DIR", dir)
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(dir, "gitconfig"))
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+
+	bin := filepath.Join(dir, "bin")
+	if err := os.Mkdir(bin, 0755); err != nil {
+		t.Fatal(err)
+	}
+	script := "#!/bin/sh\n[ \"$*\" = \"auth token --hostname github.com\" ] && echo gho_dummy\n"
+	if err := os.WriteFile(filepath.Join(bin, "gh"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	token, source, err := githubToken(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "gho_dummy" || source != "gh auth token" {
+		t.Errorf("got: %q from %q, expected: %q from %q", token, source, "gho_dummy", "gh auth token")
+	}
+}
+
+func TestGitHubToken_gitConfig(t *testing.T) {
+	dir := t.TempDir()
+	for _, k := range []string{"GH_TOKEN", "GITHUB_TOKEN", "GH_ENTERPRISE_TOKEN", "GITHUB_ENTERPRISE_TOKEN"} {
+		t.Setenv(k, "")
+	}
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("GH_CONFIG_DIR", dir)
+	gitconfig := filepath.Join(dir, "gitconfig")
+	if err := os.WriteFile(gitconfig, []byte("[github]\n\ttoken = ghp_gitconfig\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GIT_CONFIG_GLOBAL", gitconfig)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	// gh auth token comes after git config github.token
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	if err := os.WriteFile(filepath.Join(dir, "gh"), []byte("#!/bin/sh\necho gho_dummy\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	token, source, err := githubToken(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "ghp_gitconfig" || source != "git config github.token or the config of hub" {
+		t.Errorf("got: %q from %q, expected: %q from git config", token, source, "ghp_gitconfig")
+	}
+}
@@ -0,0 +1,179 @@
+package tagpr
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// splitVersionFile splits the version file spec "path#locator" into the path and the locator.
+// e.g. "config.json#/metadata/version" for the JSON Pointer (RFC 6901) or
+// "manifest.yaml#spec.version" for the dotted path of YAML
+// The options of the spec like "path?vPrefix=true#locator" are dropped from the path.
+func splitVersionFile(spec string) (fpath, locator string) {
+	if i := strings.Index(spec, "#"); i >= 0 {
+		spec, locator = spec[:i], spec[i+1:]
+	}
+	if i := strings.Index(spec, "?"); i >= 0 {
+		spec = spec[:i]
+	}
+	return spec, locator
+}
+
+// versionFileVPrefix returns the vPrefix option of the version file spec like
+// "VERSION?vPrefix=true", or nil if it is not specified.
+func versionFileVPrefix(spec string) (*bool, error) {
+	if i := strings.Index(spec, "#"); i >= 0 {
+		spec = spec[:i]
+	}
+	i := strings.Index(spec, "?")
+	if i < 0 {
+		return nil, nil
+	}
+	opts, err := url.ParseQuery(spec[i+1:])
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid options of the version file %q: %s", ErrInvalidConfig, spec, err)
+	}
+	var vPrefix *bool
+	for k, vs := range opts {
+		if k != "vPrefix" {
+			return nil, fmt.Errorf("%w: unknown option %q of the version file %q", ErrInvalidConfig, k, spec)
+		}
+		b, err := strconv.ParseBool(vs[len(vs)-1])
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid vPrefix of the version file %q: %s", ErrInvalidConfig, spec, err)
+		}
+		vPrefix = &b
+	}
+	return vPrefix, nil
+}
+
+// locateVersion returns the byte range of the version value at the locator in the file content
+func locateVersion(bs []byte, fpath, locator string) (start, end int, err error) {
+	switch ext := strings.ToLower(filepath.Ext(fpath)); {
+	case strings.HasPrefix(locator, "/"):
+		start, end, err = locateJSONPointer(bs, locator)
+	case ext == ".yaml" || ext == ".yml":
+		start, end, err = locateYAMLPath(bs, locator)
+	default:
+		return 0, 0, fmt.Errorf("%w: unsupported locator %q for %s", ErrInvalidVersionFile, locator, fpath)
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("%w: %s#%s: %s", ErrInvalidVersionFile, fpath, locator, err)
+	}
+	return start, end, nil
+}
+
+// locateJSONPointer returns the byte range of the string value at the JSON Pointer
+// excluding the quotes, to rewrite it without reformatting the whole JSON.
+func locateJSONPointer(bs []byte, pointer string) (int, int, error) {
+	var refs []string
+	for _, ref := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		refs = append(refs, strings.NewReplacer("~1", "/", "~0", "~").Replace(ref))
+	}
+	dec := json.NewDecoder(bytes.NewReader(bs))
+	return walkJSON(dec, bs, refs)
+}
+
+func walkJSON(dec *json.Decoder, bs []byte, refs []string) (int, int, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(refs) == 0 {
+		if _, ok := tok.(string); !ok {
+			return 0, 0, fmt.Errorf("the value is not a string: %v", tok)
+		}
+		// the offset is just after the closing quote
+		end := int(dec.InputOffset()) - 1
+		start := bytes.LastIndexByte(bs[:end], '"') + 1
+		return start, end, nil
+	}
+	switch tok {
+	case json.Delim('{'):
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return 0, 0, err
+			}
+			if key == refs[0] {
+				return walkJSON(dec, bs, refs[1:])
+			}
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return 0, 0, err
+			}
+		}
+	case json.Delim('['):
+		idx, err := strconv.Atoi(refs[0])
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid array index: %q", refs[0])
+		}
+		for i := 0; dec.More(); i++ {
+			if i == idx {
+				return walkJSON(dec, bs, refs[1:])
+			}
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return 0, 0, err
+			}
+		}
+	}
+	return 0, 0, fmt.Errorf("%q is not found", refs[0])
+}
+
+// locateYAMLPath returns the byte range of the scalar value at the dotted path like "spec.version"
+// excluding the quotes, to rewrite it with the comments and the ordering preserved.
+// The elements of sequences are specified by the indexes like "images.0.tag".
+func locateYAMLPath(bs []byte, path string) (int, int, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(bs, &doc); err != nil {
+		return 0, 0, err
+	}
+	if len(doc.Content) == 0 {
+		return 0, 0, fmt.Errorf("empty document")
+	}
+	node := doc.Content[0]
+	for _, key := range strings.Split(path, ".") {
+		var next *yaml.Node
+		switch node.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if node.Content[i].Value == key {
+					next = node.Content[i+1]
+					break
+				}
+			}
+		case yaml.SequenceNode:
+			if idx, err := strconv.Atoi(key); err == nil && idx >= 0 && idx < len(node.Content) {
+				next = node.Content[idx]
+			}
+		}
+		if next == nil {
+			return 0, 0, fmt.Errorf("%q is not found", key)
+		}
+		node = next
+	}
+	if node.Kind != yaml.ScalarNode {
+		return 0, 0, fmt.Errorf("the value at %q is not a scalar", path)
+	}
+	start := 0
+	for i := 1; i < node.Line; i++ {
+		start += bytes.IndexByte(bs[start:], '\n') + 1
+	}
+	start += node.Column - 1
+	if node.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle) != 0 {
+		start++
+	}
+	end := start + len(node.Value)
+	if end > len(bs) || string(bs[start:end]) != node.Value {
+		return 0, 0, fmt.Errorf("failed to locate the value at %q", path)
+	}
+	return start, end, nil
+}
@@ -0,0 +1,146 @@
+package tagpr
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLocateJSONPointer(t *testing.T) {
+	const content = `{
+  "version": "0.1.0",
+  "metadata": {
+    "name": "app",
+    "version": "v1.2.3",
+    "a/b": {"c~d": "2.0.0"}
+  },
+  "items": [{"version": "3.0.0"}, {"version": "3.1.0"}]
+}`
+	testCases := []struct {
+		pointer, expect string
+	}{
+		{"/version", "0.1.0"},
+		{"/metadata/version", "v1.2.3"},
+		{"/metadata/a~1b/c~0d", "2.0.0"},
+		{"/items/1/version", "3.1.0"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.pointer, func(t *testing.T) {
+			start, end, err := locateJSONPointer([]byte(content), tc.pointer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := content[start:end]; got != tc.expect {
+				t.Errorf("got: %s, expected: %s", got, tc.expect)
+			}
+		})
+	}
+	for _, pointer := range []string{"/metadata/missing", "/metadata", "/items/2/version"} {
+		if _, _, err := locateJSONPointer([]byte(content), pointer); err == nil {
+			t.Errorf("error should be occurred for %q", pointer)
+		}
+	}
+}
+
+func TestVersionFileWithLocator(t *testing.T) {
+	const content = "{\n  \"version\": \"0.1.0\",\n  \"metadata\": {\"version\": \"v1.2.3\"}\n}\n"
+	fpath := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(fpath, []byte(content), 0666); err != nil {
+		t.Fatal(err)
+	}
+	spec := fpath + "#/metadata/version"
+	v, err := retrieveVersionFromFile(spec, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Naked() != "1.2.3" {
+		t.Errorf("got: %s, expected: 1.2.3", v.Naked())
+	}
+	to, _ := newSemver("1.3.0")
+	replaced, err := bumpVersionFile(spec, v, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := "{\n  \"version\": \"0.1.0\",\n  \"metadata\": {\"version\": \"v1.3.0\"}\n}\n"
+	bs, _ := os.ReadFile(fpath)
+	if !replaced || string(bs) != expect {
+		t.Errorf("got:\n%s\nexpected:\n%s", bs, expect)
+	}
+}
+
+func TestLocateYAMLPath(t *testing.T) {
+	const content = `# app manifest
+apiVersion: v1
+version: 0.1.0
+spec:
+  # the version of the app
+  version: "1.2.3" # keep in sync
+  images:
+    - name: app
+      tag: 'v2.0.0'
+`
+	testCases := []struct {
+		path, expect string
+	}{
+		{"version", "0.1.0"},
+		{"spec.version", "1.2.3"},
+		{"spec.images.0.tag", "v2.0.0"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			start, end, err := locateYAMLPath([]byte(content), tc.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := content[start:end]; got != tc.expect {
+				t.Errorf("got: %s, expected: %s", got, tc.expect)
+			}
+		})
+	}
+	for _, path := range []string{"spec.missing", "spec", "spec.images.1.tag"} {
+		if _, _, err := locateYAMLPath([]byte(content), path); err == nil {
+			t.Errorf("error should be occurred for %q", path)
+		}
+	}
+}
+
+func TestBumpVersionFile_vPrefix(t *testing.T) {
+	testCases := []struct {
+		name, content, opt, expect string
+	}{
+		{"keep v", "version: v1.2.3\n", "", "version: v1.3.0\n"},
+		{"keep bare", "version: 1.2.3\n", "", "version: 1.3.0\n"},
+		{"add v", "version: 1.2.3\n", "?vPrefix=true", "version: v1.3.0\n"},
+		{"remove v", "version: v1.2.3\n", "?vPrefix=false", "version: 1.3.0\n"},
+		{"with locator", "{\"version\": \"1.2.3\"}", "?vPrefix=true#/version", "{\"version\": \"v1.3.0\"}"},
+	}
+	from, _ := newSemver("1.2.3")
+	to, _ := newSemver("1.3.0")
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fpath := filepath.Join(t.TempDir(), "version.json")
+			if err := os.WriteFile(fpath, []byte(tc.content), 0666); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := bumpVersionFile(fpath+tc.opt, from, to); err != nil {
+				t.Fatal(err)
+			}
+			if bs, _ := os.ReadFile(fpath); string(bs) != tc.expect {
+				t.Errorf("got: %q, expected: %q", bs, tc.expect)
+			}
+			v, err := retrieveVersionFromFile(fpath+tc.opt, false, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v.Naked() != "1.3.0" {
+				t.Errorf("got: %s, expected: 1.3.0", v.Naked())
+			}
+		})
+	}
+
+	for _, spec := range []string{"VERSION?vPrefix=maybe", "VERSION?prefix=true"} {
+		if _, err := versionFileVPrefix(spec); err == nil {
+			t.Errorf("%s: error should be occurred", spec)
+		}
+	}
+}
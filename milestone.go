@@ -0,0 +1,99 @@
+package tagpr
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/v47/github"
+)
+
+const milestoneAuto = "auto"
+
+var mergedPullReg = regexp.MustCompile(`^Merge pull request #([0-9]+) |\(#([0-9]+)\)$`)
+
+// mergedPullNumbers retrieves the numbers of the pull requests merged in the range from
+// the subjects of the first-parent commits, for "Create a merge commit" and "Squash and merge".
+func (tp *tagpr) mergedPullNumbers(from, to string) ([]int, error) {
+	rng := to
+	if from != "" {
+		rng = from + ".." + to
+	}
+	out, _, err := tp.c.Git("log", "--first-parent", "--format=%s", rng)
+	if err != nil {
+		return nil, err
+	}
+	var nums []int
+	for _, subject := range strings.Split(out, "\n") {
+		m := mergedPullReg.FindStringSubmatch(strings.TrimSpace(subject))
+		if len(m) < 3 {
+			continue
+		}
+		numStr := m[1]
+		if numStr == "" {
+			numStr = m[2]
+		}
+		if n, err := strconv.Atoi(numStr); err == nil {
+			nums = append(nums, n)
+		}
+	}
+	return nums, nil
+}
+
+// findMilestone finds the milestone by the title. It returns nil if not found.
+func (tp *tagpr) findMilestone(ctx context.Context, title string) (*github.Milestone, error) {
+	opt := &github.MilestoneListOptions{State: "all", ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		milestones, resp, err := tp.gh.Issues.ListMilestones(ctx, tp.owner, tp.repo, opt)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range milestones {
+			if m.GetTitle() == title {
+				return m, nil
+			}
+		}
+		if resp.NextPage == 0 {
+			return nil, nil
+		}
+		opt.Page = resp.NextPage
+	}
+}
+
+// attachMilestone finds or creates the milestone named after the version, and attaches
+// the release pull request and the included pull requests to it.
+func (tp *tagpr) attachMilestone(ctx context.Context, title string, pr *github.PullRequest, included []int) error {
+	m, err := tp.findMilestone(ctx, title)
+	if err != nil {
+		return err
+	}
+	if m == nil {
+		m, _, err = tp.gh.Issues.CreateMilestone(ctx, tp.owner, tp.repo, &github.Milestone{
+			Title: github.String(title),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	for _, num := range append([]int{pr.GetNumber()}, included...) {
+		if _, _, err := tp.gh.Issues.Edit(ctx, tp.owner, tp.repo, num, &github.IssueRequest{
+			Milestone: m.Number,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// closeMilestone closes the milestone named after the version if exists
+func (tp *tagpr) closeMilestone(ctx context.Context, title string) error {
+	m, err := tp.findMilestone(ctx, title)
+	if err != nil || m == nil || m.GetState() == "closed" {
+		return err
+	}
+	_, _, err = tp.gh.Issues.EditMilestone(ctx, tp.owner, tp.repo, m.GetNumber(), &github.Milestone{
+		State: github.String("closed"),
+	})
+	return err
+}
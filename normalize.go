@@ -0,0 +1,54 @@
+package tagpr
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// the non-standard versions in the version files like "01.02.03" or "1.2"
+const looseVersionRegBase = `v?([0-9]+(?:\.[0-9]+)+)`
+
+var (
+	// The gap after the "version" keyword is non-greedy not to capture only the tail of the
+	// version, unlike versionReg which requires the three numbers.
+	looseVersionReg    = regexp.MustCompile(`(?i)((?:^|[^-_0-9a-zA-Z])version[^-_0-9a-zA-Z].{0,50}?)` + looseVersionRegBase)
+	looseVersionAnyReg = regexp.MustCompile(`(?:v|\b)` + looseVersionRegBase + `\b`)
+)
+
+// normalizeVersion repairs the non-standard version to the valid semver by stripping the
+// leading zeros and padding the missing patch version. e.g. "01.02.03" => "1.2.3", "1.2" => "1.2.0"
+func normalizeVersion(ver string) (string, error) {
+	parts := strings.Split(strings.TrimPrefix(ver, "v"), ".")
+	if len(parts) < 2 || len(parts) > 3 {
+		return "", fmt.Errorf("cannot normalize the version %q to the semantic version", ver)
+	}
+	for len(parts) < 3 {
+		parts = append(parts, "0")
+	}
+	for i, p := range parts {
+		n, err := strconv.ParseUint(p, 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("cannot normalize the version %q to the semantic version: %w", ver, err)
+		}
+		parts[i] = strconv.FormatUint(n, 10)
+	}
+	return strings.Join(parts, "."), nil
+}
+
+// replaceLooseVersion replaces the first version in the non-standard form equivalent to
+// the from version with the canonical form of the to version, preferring the one after
+// the "version" keyword. It returns nil if it is not found.
+func replaceLooseVersion(bs []byte, from, to *semv, vPrefix *bool) []byte {
+	for _, reg := range []*regexp.Regexp{looseVersionReg, looseVersionAnyReg} {
+		for _, loc := range reg.FindAllSubmatchIndex(bs, -1) {
+			start, end := loc[len(loc)-2], loc[len(loc)-1]
+			if ver, err := normalizeVersion(string(bs[start:end])); err != nil || ver != from.Naked() {
+				continue
+			}
+			return replaceVersion(bs, start, end, to, vPrefix)
+		}
+	}
+	return nil
+}
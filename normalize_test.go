@@ -0,0 +1,86 @@
+package tagpr
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNormalizeVersion(t *testing.T) {
+	testCases := []struct {
+		input, expect string
+	}{
+		{"01.02.03", "1.2.3"},
+		{"1.2", "1.2.0"},
+		{"v1.02", "1.2.0"},
+		{"1.2.3", "1.2.3"},
+	}
+	for _, tc := range testCases {
+		got, err := normalizeVersion(tc.input)
+		if err != nil {
+			t.Errorf("%s: %s", tc.input, err)
+			continue
+		}
+		if got != tc.expect {
+			t.Errorf("%s: got: %s, expected: %s", tc.input, got, tc.expect)
+		}
+	}
+	for _, input := range []string{"1", "1.2.3.4", "1.x"} {
+		if _, err := normalizeVersion(input); err == nil {
+			t.Errorf("%s: error should be occurred", input)
+		}
+	}
+}
+
+func TestBumpVersionFile_normalize(t *testing.T) {
+	testCases := []struct {
+		name, content, expect string
+	}{
+		{"leading zeros", "version: 01.02.03\n", "version: 1.3.0\n"},
+		{"missing patch", "VERSION = '1.2'\n", "VERSION = '1.3.0'\n"},
+		{"not equivalent", "version: 1.2.5\n", ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fpath := filepath.Join(t.TempDir(), "version.txt")
+			if err := os.WriteFile(fpath, []byte(tc.content), 0666); err != nil {
+				t.Fatal(err)
+			}
+			v, err := retrieveVersionFromFile(fpath, false, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			to, _ := newSemver("1.3.0")
+			txn := newFileTxn(true)
+			replaced, err := txn.bumpVersionFile(fpath, v, to)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.expect == "" {
+				// bump from the version not in the file
+				from, _ := newSemver("1.2.0")
+				if replaced, _ := newFileTxn(true).bumpVersionFile(fpath, from, to); replaced {
+					t.Error("the version should not be replaced")
+				}
+				return
+			}
+			if !replaced {
+				t.Fatal("the version should be replaced")
+			}
+			if err := txn.commit(); err != nil {
+				t.Fatal(err)
+			}
+			if bs, _ := os.ReadFile(fpath); string(bs) != tc.expect {
+				t.Errorf("got: %q, expected: %q", bs, tc.expect)
+			}
+		})
+	}
+
+	fpath := filepath.Join(t.TempDir(), "version.txt")
+	if err := os.WriteFile(fpath, []byte("version: 1.2.3.4\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := retrieveVersionFromFile(fpath, false, true); err == nil {
+		t.Error("error should be occurred for the unfixable version")
+	}
+}
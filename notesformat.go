@@ -0,0 +1,168 @@
+package tagpr
+
+import (
+	"os"
+	"regexp"
+	"strings"
+)
+
+const (
+	notesFormatMarkdown = "markdown"
+	notesFormatRST      = "rst"
+)
+
+// notesFormat is the format of the changelog file. The notes are generated in markdown by
+// gh2changelog, and converted into the format on writing the changelog file.
+type notesFormat interface {
+	// file returns the path of the changelog file
+	file() string
+	// render converts the changelog section in markdown into the format
+	render(section string) string
+	// update puts the changelog sections in markdown at the top of the changelog file
+	update(gch changelogUpdater, file string, sections []string) error
+}
+
+// changelogUpdater updates CHANGELOG.md, implemented by gh2changelog
+type changelogUpdater interface {
+	Update(section string, mode int) (string, error)
+}
+
+func newNotesFormat(name string) notesFormat {
+	if name == notesFormatRST {
+		return rstFormat{}
+	}
+	return markdownFormat{}
+}
+
+type markdownFormat struct{}
+
+func (markdownFormat) file() string {
+	return "CHANGELOG.md"
+}
+
+func (markdownFormat) render(section string) string {
+	return section
+}
+
+// update lets gh2changelog update CHANGELOG.md, which knows the layout of the file.
+func (markdownFormat) update(gch changelogUpdater, _ string, sections []string) error {
+	_, err := gch.Update(strings.Join(sections, "\n"), 0)
+	return err
+}
+
+type rstFormat struct{}
+
+func (rstFormat) file() string {
+	return "CHANGELOG.rst"
+}
+
+func (f rstFormat) update(_ changelogUpdater, file string, sections []string) error {
+	rendered := make([]string, 0, len(sections))
+	for _, s := range sections {
+		rendered = append(rendered, f.render(s))
+	}
+	return updateRSTChangelog(file, rendered...)
+}
+
+var (
+	mdHeadingReg    = regexp.MustCompile(`^(#{1,6}) (.*)$`)
+	mdLinkReg       = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	mdCodeReg       = regexp.MustCompile("`([^`]+)`")
+	mdInlineReg     = regexp.MustCompile("`[^`]+`|" + mdLinkReg.String())
+	mdStrongReg     = regexp.MustCompile(`(^|\W)__([^_\s](?:[^_]*[^_\s])?)__($|\W)`)
+	mdEmReg         = regexp.MustCompile(`(^|\W)_([^_\s](?:[^_]*[^_\s])?)_($|\W)`)
+	mdCommentReg    = regexp.MustCompile(`(?s)<!--.*?-->`)
+	rstHeadingChars = map[int]string{1: "=", 2: "-", 3: "~", 4: "^", 5: "\"", 6: "'"}
+)
+
+// render converts the markdown generated by gh2changelog, the headings, the list items,
+// the links, the inline codes and the underscore emphasis, into reStructuredText. The
+// headings are underlined by "=" for "#", "-" for "##", "~" for "###" and so on.
+func (rstFormat) render(section string) string {
+	section = mdCommentReg.ReplaceAllString(section, "")
+	var lines []string
+	blank := func() {
+		if len(lines) > 0 && lines[len(lines)-1] != "" {
+			lines = append(lines, "")
+		}
+	}
+	inList := false
+	for _, line := range strings.Split(section, "\n") {
+		line = strings.TrimRight(line, " \t")
+		if line == "" {
+			blank()
+			inList = false
+			continue
+		}
+		if m := mdHeadingReg.FindStringSubmatch(line); m != nil {
+			text := rstInline(m[2])
+			blank()
+			lines = append(lines, text, strings.Repeat(rstHeadingChars[len(m[1])], len([]rune(text))), "")
+			inList = false
+			continue
+		}
+		isItem := strings.HasPrefix(line, "* ") || strings.HasPrefix(line, "- ")
+		if isItem {
+			line = "- " + line[2:]
+			if !inList {
+				// a list needs a blank line before it
+				blank()
+			}
+		} else if inList {
+			blank()
+		}
+		inList = isItem
+		lines = append(lines, rstInline(line))
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n")) + "\n"
+}
+
+// rstInline converts the inline codes, the links and the underscore emphasis. The emphasis
+// is converted only outside of the inline codes and the links, so that the identifiers and
+// the URLs containing underscores are kept as is.
+func rstInline(s string) string {
+	var b strings.Builder
+	last := 0
+	for _, loc := range mdInlineReg.FindAllStringIndex(s, -1) {
+		b.WriteString(rstEmphasis(s[last:loc[0]]))
+		m := s[loc[0]:loc[1]]
+		if strings.HasPrefix(m, "`") {
+			b.WriteString(mdCodeReg.ReplaceAllString(m, "``$1``"))
+		} else {
+			b.WriteString(mdLinkReg.ReplaceAllString(m, "`$1 <$2>`__"))
+		}
+		last = loc[1]
+	}
+	b.WriteString(rstEmphasis(s[last:]))
+	return b.String()
+}
+
+func rstEmphasis(s string) string {
+	s = mdStrongReg.ReplaceAllString(s, "$1**$2**$3")
+	return mdEmReg.ReplaceAllString(s, "$1*$2*$3")
+}
+
+const rstChangelogTitle = "Changelog\n=========\n"
+
+// updateRSTChangelog puts the sections at the top of the changelog file in reStructuredText
+// below the title. The title is written if the file doesn't exist.
+func updateRSTChangelog(fpath string, sections ...string) error {
+	bs, err := os.ReadFile(fpath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	content := strings.TrimLeft(string(bs), "\n")
+	if !strings.HasPrefix(content, rstChangelogTitle) {
+		content = rstChangelogTitle + "\n" + content
+	}
+	rest := strings.TrimLeft(content[len(rstChangelogTitle):], "\n")
+	var b strings.Builder
+	b.WriteString(rstChangelogTitle)
+	for _, s := range sections {
+		b.WriteString("\n" + strings.TrimSpace(s) + "\n")
+	}
+	if rest != "" {
+		b.WriteString("\n" + rest)
+	}
+	return os.WriteFile(fpath, []byte(b.String()), 0644)
+}
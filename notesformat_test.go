@@ -0,0 +1,97 @@
+package tagpr
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRSTFormat_render(t *testing.T) {
+	const section = "## [v1.2.0](https://github.com/Songmu/tagpr/compare/v1.1.0...v1.2.0) - 2024-06-01\n" +
+		"<!-- Release notes generated using configuration in .github/release.yml at main -->\n" +
+		"### What's Changed\n" +
+		"- Add `--force` flag by @Songmu in https://github.com/Songmu/tagpr/pull/1\n" +
+		"- Fix [the typo](https://example.com) by @Songmu in https://github.com/Songmu/tagpr/pull/2\n" +
+		"- Document _the emphasis_ and __the strong__ of `snake_case_name` by @some_user_ in https://github.com/Songmu/tagpr/pull/3\n" +
+		"\n" +
+		"**Full Changelog**: https://github.com/Songmu/tagpr/compare/v1.1.0...v1.2.0\n"
+	heading := "`v1.2.0 <https://github.com/Songmu/tagpr/compare/v1.1.0...v1.2.0>`__ - 2024-06-01"
+	underline := ""
+	for range heading {
+		underline += "-"
+	}
+	expect := heading + "\n" + underline + "\n" +
+		"\n" +
+		"What's Changed\n" +
+		"~~~~~~~~~~~~~~\n" +
+		"\n" +
+		"- Add ``--force`` flag by @Songmu in https://github.com/Songmu/tagpr/pull/1\n" +
+		"- Fix `the typo <https://example.com>`__ by @Songmu in https://github.com/Songmu/tagpr/pull/2\n" +
+		"- Document *the emphasis* and **the strong** of ``snake_case_name`` by @some_user_ in https://github.com/Songmu/tagpr/pull/3\n" +
+		"\n" +
+		"**Full Changelog**: https://github.com/Songmu/tagpr/compare/v1.1.0...v1.2.0\n"
+	if got := (rstFormat{}).render(section); got != expect {
+		t.Errorf("got:\n%s\nexpect:\n%s", got, expect)
+	}
+	if got := (markdownFormat{}).render(section); got != section {
+		t.Errorf("markdown should be as is, but got:\n%s", got)
+	}
+}
+
+func TestUpdateRSTChangelog(t *testing.T) {
+	fpath := filepath.Join(t.TempDir(), "CHANGELOG.rst")
+	if err := updateRSTChangelog(fpath, "v1.1.0\n------\n", "v1.0.0\n------\n"); err != nil {
+		t.Fatal(err)
+	}
+	if err := updateRSTChangelog(fpath, "v1.2.0\n------\n"); err != nil {
+		t.Fatal(err)
+	}
+	bs, err := os.ReadFile(fpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := rstChangelogTitle +
+		"\nv1.2.0\n------\n" +
+		"\nv1.1.0\n------\n" +
+		"\nv1.0.0\n------\n"
+	if string(bs) != expect {
+		t.Errorf("got:\n%s\nexpect:\n%s", bs, expect)
+	}
+}
+
+type changelogUpdaterFunc func(section string, mode int) (string, error)
+
+func (f changelogUpdaterFunc) Update(section string, mode int) (string, error) {
+	return f(section, mode)
+}
+
+func TestNotesFormat_update(t *testing.T) {
+	sections := []string{"## v1.2.0\n- Add feature\n", "## v1.1.0\n- Fix bug\n"}
+
+	var updated string
+	gch := changelogUpdaterFunc(func(section string, _ int) (string, error) {
+		updated = section
+		return section, nil
+	})
+	if err := (markdownFormat{}).update(gch, "CHANGELOG.md", sections); err != nil {
+		t.Fatal(err)
+	}
+	if expect := "## v1.2.0\n- Add feature\n\n## v1.1.0\n- Fix bug\n"; updated != expect {
+		t.Errorf("got:\n%s\nexpect:\n%s", updated, expect)
+	}
+
+	fpath := filepath.Join(t.TempDir(), "CHANGELOG.rst")
+	if err := (rstFormat{}).update(nil, fpath, sections); err != nil {
+		t.Fatal(err)
+	}
+	bs, err := os.ReadFile(fpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := rstChangelogTitle +
+		"\nv1.2.0\n------\n\n- Add feature\n" +
+		"\nv1.1.0\n------\n\n- Fix bug\n"
+	if string(bs) != expect {
+		t.Errorf("got:\n%s\nexpect:\n%s", bs, expect)
+	}
+}
@@ -0,0 +1,68 @@
+package tagpr
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+)
+
+// writeVersion writes the computed next version and the tag name to the file in
+// "key=value" format, which can be used for $GITHUB_OUTPUT and so on. The values are
+// empty if there is nothing to release, not to leave the version of the previous run.
+func (r *result) writeVersion(fpath string) error {
+	var version, tag string
+	if r.nextVersion != nil {
+		version, tag = r.nextVersion.Naked(), r.nextVersion.Tag()
+	}
+	content := fmt.Sprintf("version=%s\ntag=%s\n", version, tag)
+	return os.WriteFile(fpath, []byte(content), 0666)
+}
+
+// writeGitHubOutput appends the result of the run to the $GITHUB_OUTPUT file
+// so that it can be used from the downstream steps in GitHub Actions.
+func (r *result) writeGitHubOutput(fpath string) error {
+	var version, tag, prNum string
+	if r.nextVersion != nil {
+		version, tag = r.nextVersion.Naked(), r.nextVersion.Tag()
+	}
+	if r.pullRequest != nil && r.pullRequest.Number != nil {
+		prNum = strconv.Itoa(r.pullRequest.GetNumber())
+	}
+	f, err := os.OpenFile(fpath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0666)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = fmt.Fprintf(f, "tag=%s\nversion=%s\npull_request_number=%s\ncreated=%t\nreason=%s\n",
+		tag, version, prNum, r.outcome == outcomeCreated, r.reason)
+	return err
+}
+
+type jsonResult struct {
+	Outcome           string `json:"outcome"`
+	Reason            string `json:"reason,omitempty"`
+	Message           string `json:"message,omitempty"`
+	Tag               string `json:"tag,omitempty"`
+	Version           string `json:"version,omitempty"`
+	PullRequestNumber int    `json:"pull_request_number,omitempty"`
+}
+
+// writeJSON writes the result of the run in JSON for --output json.
+func (r *result) writeJSON(w io.Writer) error {
+	jr := jsonResult{
+		Outcome: r.outcome.String(),
+		Reason:  r.reason,
+		Message: r.message,
+	}
+	if r.nextVersion != nil {
+		jr.Tag, jr.Version = r.nextVersion.Tag(), r.nextVersion.Naked()
+	}
+	if r.pullRequest != nil {
+		jr.PullRequestNumber = r.pullRequest.GetNumber()
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(jr)
+}
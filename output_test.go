@@ -0,0 +1,64 @@
+package tagpr
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-github/v47/github"
+)
+
+func TestResult_writeJSON(t *testing.T) {
+	v, err := newSemver("v1.2.3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := result{nextVersion: v, pullRequest: &github.PullRequest{Number: github.Int(12)}}
+	r.reason, r.message = reasonUpToDate, "the release pull request #12 is up to date"
+	var buf bytes.Buffer
+	if err := r.writeJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	expect := `{
+  "outcome": "noop",
+  "reason": "up_to_date",
+  "message": "the release pull request #12 is up to date",
+  "tag": "v1.2.3",
+  "version": "1.2.3",
+  "pull_request_number": 12
+}
+`
+	if got := buf.String(); got != expect {
+		t.Errorf("got:\n%s\nexpected:\n%s", got, expect)
+	}
+}
+
+func TestResult_writeVersion(t *testing.T) {
+	fpath := filepath.Join(t.TempDir(), "version.env")
+	v, err := newSemver("v1.2.3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		name   string
+		r      result
+		expect string
+	}{
+		{"next version", result{nextVersion: v}, "version=1.2.3\ntag=v1.2.3\n"},
+		// the version of the previous run is not left
+		{"nothing to release", result{reason: reasonNoNewChanges}, "version=\ntag=\n"},
+	}
+	for _, tc := range testCases {
+		if err := tc.r.writeVersion(fpath); err != nil {
+			t.Fatal(err)
+		}
+		bs, err := os.ReadFile(fpath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(bs); got != tc.expect {
+			t.Errorf("%s: got: %q, expected: %q", tc.name, got, tc.expect)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package tagpr
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-github/v47/github"
+)
+
+const prCommentMarker = "<!-- tagpr:comment -->"
+
+// prCommentBody renders the summary of the run posted as the comment with tagpr.prComment
+func prCommentBody(prev, next, details string) string {
+	summary := fmt.Sprintf("Next version: **%s**", next)
+	if prev != "" {
+		summary += fmt.Sprintf(" (the latest: %s)", prev)
+	}
+	return prCommentMarker + "\n" + summary + details
+}
+
+// upsertPRComment updates the comment of the tagpr on the pull request found by the marker,
+// or creates it if not found.
+func (tp *tagpr) upsertPRComment(ctx context.Context, num int, body string) error {
+	opt := &github.IssueListCommentsOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		comments, resp, err := tp.gh.Issues.ListComments(ctx, tp.owner, tp.repo, num, opt)
+		if err != nil {
+			return err
+		}
+		for _, c := range comments {
+			if !strings.HasPrefix(c.GetBody(), prCommentMarker) {
+				continue
+			}
+			if c.GetBody() == body {
+				return nil
+			}
+			_, _, err := tp.gh.Issues.EditComment(ctx, tp.owner, tp.repo, c.GetID(), &github.IssueComment{
+				Body: github.String(body),
+			})
+			return err
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+	_, _, err := tp.gh.Issues.CreateComment(ctx, tp.owner, tp.repo, num, &github.IssueComment{
+		Body: github.String(body),
+	})
+	return err
+}
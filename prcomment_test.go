@@ -0,0 +1,89 @@
+package tagpr
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v47/github"
+)
+
+func TestPRCommentBody(t *testing.T) {
+	got := prCommentBody("v1.0.0", "v1.1.0", "\n\n<details>diff</details>")
+	if !strings.HasPrefix(got, prCommentMarker+"\n") {
+		t.Errorf("the comment should start with the marker: %s", got)
+	}
+	for _, s := range []string{"**v1.1.0**", "v1.0.0", "<details>diff</details>"} {
+		if !strings.Contains(got, s) {
+			t.Errorf("the comment should contain %q: %s", s, got)
+		}
+	}
+	if got := prCommentBody("", "v0.0.1", ""); strings.Contains(got, "latest") {
+		t.Errorf("the latest version should be omitted for the first release: %s", got)
+	}
+}
+
+func TestUpsertPRComment(t *testing.T) {
+	fake := newFakeGitHub(t, nil)
+	tp := &tagpr{gh: fake.client(), owner: testOwner, repo: testRepo}
+	ctx := context.Background()
+	// the comment of the tagpr on the second page
+	for i := 0; i < 100; i++ {
+		fake.comments[1] = append(fake.comments[1], &github.IssueComment{
+			ID: github.Int64(int64(i + 1)), Body: github.String(fmt.Sprintf("comment %d", i))})
+	}
+	fake.nextID = 100
+
+	if err := tp.upsertPRComment(ctx, 1, prCommentBody("", "v0.0.1", "")); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.comments[1]) != 101 {
+		t.Fatalf("the comment should be created: %d comments", len(fake.comments[1]))
+	}
+	if err := tp.upsertPRComment(ctx, 1, prCommentBody("", "v0.1.0", "")); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.comments[1]) != 101 {
+		t.Fatalf("the comment should be updated instead of created: %d comments", len(fake.comments[1]))
+	}
+	if got := fake.comments[1][100].GetBody(); got != prCommentBody("", "v0.1.0", "") {
+		t.Errorf("got: %s", got)
+	}
+	if got := fake.comments[1][0].GetBody(); got != "comment 0" {
+		t.Errorf("the other comments should be intact, but got: %s", got)
+	}
+}
+
+func TestRun_prComment(t *testing.T) {
+	r := newTestRepo(t, "[tagpr]\n\treleaseBranch = main\n\tversionFile = version.txt\n\tvPrefix = true\n"+
+		"\tprComment = true\n\tshowVersionDiff = true\n")
+	fake := newFakeGitHub(t, r)
+	r.pushChange("a.txt")
+	if _, err := r.runTagPR(fake, "main"); err != nil {
+		t.Fatal(err)
+	}
+	fake.mu.Lock()
+	fake.pulls[0].Body = github.String("Curated by the maintainers\n" + strings.Replace(
+		fake.pulls[0].GetBody(), "## What's Changed", "## Edited in the region", 1))
+	fake.mu.Unlock()
+
+	r.pushChange("b.txt")
+	if _, err := r.runTagPR(fake, "main"); err != nil {
+		t.Fatal(err)
+	}
+	body := fake.openPulls()[0].GetBody()
+	if !strings.HasPrefix(body, "Curated by the maintainers\n"+bodyStartMarker) {
+		t.Errorf("the body outside the region should be intact: %s", body)
+	}
+	if strings.Contains(body, "Edited in the region") || !strings.Contains(body, "## What's Changed") {
+		t.Errorf("the region should be rewritten: %s", body)
+	}
+	if strings.Contains(body, "Changes of the version files") {
+		t.Errorf("the details should be posted as the comment instead: %s", body)
+	}
+	coms := fake.comments[1]
+	if len(coms) != 1 || !strings.Contains(coms[0].GetBody(), "Changes of the version files") {
+		t.Errorf("the comment should have the details: %v", coms)
+	}
+}
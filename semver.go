@@ -1,6 +1,11 @@
 package tagpr
 
 import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
 	"github.com/Masterminds/semver/v3"
 	"github.com/google/go-github/v47/github"
 )
@@ -9,6 +14,7 @@ type semv struct {
 	v *semver.Version
 
 	vPrefix bool
+	format  tagFormat
 }
 
 func newSemver(v string) (*semv, error) {
@@ -28,34 +34,224 @@ func (sv *semv) Naked() string {
 
 func (sv *semv) Tag() string {
 	if sv.vPrefix {
-		return "v" + sv.Naked()
+		return sv.format.name("v" + sv.Naked())
 	}
-	return sv.Naked()
+	return sv.format.name(sv.Naked())
 }
 
-func (sv *semv) GuessNext(labels []*github.Label) *semv {
-	var isMajor, isMinor bool
-	for _, l := range labels {
-		switch l.GetName() {
-		case autoLableName + ":major", autoLableName + "/major":
-			isMajor = true
-		case autoLableName + ":minor", autoLableName + "/minor":
-			isMinor = true
+// derive returns the new version in the same tagging convention
+func (sv *semv) derive(v *semver.Version) *semv {
+	return &semv{
+		v:       v,
+		vPrefix: sv.vPrefix,
+		format:  sv.format,
+	}
+}
+
+// latestSemver returns the tag of the latest version from the tags in the format.
+// Pre-releases and versions with build metadata are ignored. Tags that can not be
+// parsed as semver after stripping the prefix and the suffix are returned as skipped.
+func latestSemver(tags []string, tf tagFormat) (latest string, skipped []string) {
+	var latestVer *semver.Version
+	for _, tag := range tags {
+		ver, ok := tf.parse(tag)
+		if !ok {
+			continue
+		}
+		v, err := semver.NewVersion(ver)
+		if err != nil {
+			skipped = append(skipped, tag)
+			continue
+		}
+		if v.Prerelease() != "" || v.Metadata() != "" {
+			continue
+		}
+		if latestVer == nil || v.GreaterThan(latestVer) {
+			latest, latestVer = tag, v
+		}
+	}
+	return latest, skipped
+}
+
+// candidateSuffix is the pre-release of the candidate tag on the merge of the release pull request
+// with tagpr.tagReleaseOnly, e.g. "v1.2.3-rc", which is promoted to the final tag "v1.2.3" later.
+const candidateSuffix = "-rc"
+
+// newerCandidate returns the tag of the latest candidate from the tags in the format if it is newer
+// than the latest tag, that is, not promoted yet, or the latest tag otherwise.
+func newerCandidate(tags []string, tf tagFormat, latest string) string {
+	var latestVer *semver.Version
+	if ver, ok := tf.parse(latest); ok {
+		latestVer, _ = semver.NewVersion(ver)
+	}
+	for _, tag := range tags {
+		ver, ok := tf.parse(tag)
+		if !ok {
+			continue
+		}
+		v, err := semver.NewVersion(ver)
+		if err != nil || "-"+v.Prerelease() != candidateSuffix || v.Metadata() != "" {
+			continue
+		}
+		final, _ := v.SetPrerelease("")
+		if latestVer == nil || final.GreaterThan(latestVer) {
+			latest, latestVer = tag, &final
+		}
+	}
+	return latest
+}
+
+// checkRegression returns ErrVersionRegression if the next version is not greater than the
+// version of the latest tag, e.g. by the manual override or the version files edited by mistake.
+func checkRegression(next *semv, latestTag string) error {
+	if latestTag == "" {
+		return nil
+	}
+	ver, ok := next.format.parse(latestTag)
+	if !ok {
+		return nil
+	}
+	latest, err := semver.NewVersion(ver)
+	if err != nil {
+		return nil
+	}
+	// the candidate of tagpr.tagReleaseOnly is compared as the final version
+	if l, err := latest.SetPrerelease(""); err == nil {
+		latest = &l
+	}
+	if !next.v.GreaterThan(latest) {
+		return fmt.Errorf("%w: the next version %s is not greater than the latest tag %s",
+			ErrVersionRegression, next.Tag(), latestTag)
+	}
+	return nil
+}
+
+// recentSemvers returns the latest n tags of the format other than the excluded one in
+// descending order of the versions. The pre-releases are ignored as well as latestSemver.
+func recentSemvers(tags []string, tf tagFormat, n int, exclude string) []string {
+	type tagVer struct {
+		tag string
+		v   *semver.Version
+	}
+	var tvs []tagVer
+	for _, tag := range tags {
+		ver, ok := tf.parse(tag)
+		if !ok || tag == exclude {
+			continue
 		}
+		v, err := semver.NewVersion(ver)
+		if err != nil || v.Prerelease() != "" || v.Metadata() != "" {
+			continue
+		}
+		tvs = append(tvs, tagVer{tag, v})
+	}
+	sort.SliceStable(tvs, func(i, j int) bool {
+		return tvs[i].v.GreaterThan(tvs[j].v)
+	})
+	var recent []string
+	for i := 0; i < len(tvs) && i < n; i++ {
+		recent = append(recent, tvs[i].tag)
 	}
+	return recent
+}
+
+const (
+	bumpPatch = "patch"
+	bumpMinor = "minor"
+	bumpMajor = "major"
+)
 
+func (sv *semv) GuessNext(labels []*github.Label) *semv {
+	return sv.Next(bumpFromLabels(labels))
+}
+
+// Next returns the next version incremented by the bump level.
+// The patch version is incremented if the bump level is unknown.
+func (sv *semv) Next(bump string) *semv {
 	var nextv semver.Version
-	switch {
-	case isMajor:
+	switch bump {
+	case bumpMajor:
 		nextv = sv.v.IncMajor()
-	case isMinor:
+	case bumpMinor:
 		nextv = sv.v.IncMinor()
 	default:
 		nextv = sv.v.IncPatch()
 	}
 
-	return &semv{
-		v:       &nextv,
-		vPrefix: sv.vPrefix,
+	return sv.derive(&nextv)
+}
+
+// DevVersion returns the next patch version with the suffix for the development
+// between releases. e.g. "1.3.1-dev" for "1.3.0" with the suffix "-dev"
+func (sv *semv) DevVersion(suffix string) (*semv, error) {
+	return sv.Next(bumpPatch).WithSuffix(suffix)
+}
+
+// WithSuffix returns the version with the suffix as the pre-release. e.g. "1.3.0-SNAPSHOT"
+func (sv *semv) WithSuffix(suffix string) (*semv, error) {
+	v, err := sv.v.SetPrerelease(strings.TrimPrefix(suffix, "-"))
+	if err != nil {
+		return nil, err
+	}
+	return sv.derive(&v), nil
+}
+
+func bumpFromLabels(labels []*github.Label) string {
+	var bump string
+	for _, l := range labels {
+		switch strings.ToLower(strings.TrimSpace(l.GetName())) {
+		case autoLableName + ":major", autoLableName + "/major":
+			return bumpMajor
+		case autoLableName + ":minor", autoLableName + "/minor":
+			bump = bumpMinor
+		}
+	}
+	return bump
+}
+
+// bumpSource is the bump level detected from a signal like the labels
+type bumpSource struct {
+	name, bump string
+}
+
+// resolveBump resolves the bump level from the sources given in the order of precedence.
+// The first source with the bump level wins, and the patch is the default. The tagpr
+// gives the following sources in this order.
+//
+//  1. the breaking changes, i.e. the merged pull requests with tagpr.breakingLabels, if tagpr.majorOnBreaking is specified
+//  2. the labels of the release pull request, e.g. "tagpr:minor"
+//  3. the titles of the release pull request and the merged pull requests by tagpr.titleBumpPattern
+//
+// The next version specified in the body of the release pull request takes precedence
+// over all of them, as it is not a bump level. It returns the name of the winning source too.
+func resolveBump(sources ...bumpSource) (bump, source string) {
+	for _, s := range sources {
+		if s.bump != "" {
+			return s.bump, s.name
+		}
+	}
+	return bumpPatch, "default"
+}
+
+// bumpFromTitles detects the bump level from the titles by the regexp with a capture
+// group that captures "major", "minor" or "patch". The highest level is adopted.
+func bumpFromTitles(reg *regexp.Regexp, titles []string) string {
+	var bump string
+	for _, t := range titles {
+		m := reg.FindStringSubmatch(t)
+		if len(m) < 2 {
+			continue
+		}
+		switch strings.ToLower(m[1]) {
+		case bumpMajor:
+			return bumpMajor
+		case bumpMinor:
+			bump = bumpMinor
+		case bumpPatch:
+			if bump == "" {
+				bump = bumpPatch
+			}
+		}
 	}
+	return bump
 }
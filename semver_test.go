@@ -0,0 +1,261 @@
+package tagpr
+
+import (
+	"errors"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/google/go-github/v47/github"
+)
+
+func TestLatestSemver(t *testing.T) {
+	testCases := []struct {
+		name        string
+		tags        []string
+		format      tagFormat
+		expect      string
+		expectSkips []string
+	}{{
+		name:        "mixed with junk tags",
+		tags:        []string{"v0.1.0", "build-123", "v1.2.0", "v1.10.0-rc.1", "release", "v1.9.3", "v1.3.0+meta"},
+		expect:      "v1.9.3",
+		expectSkips: []string{"build-123", "release"},
+	}, {
+		name:   "no tags",
+		tags:   nil,
+		expect: "",
+	}, {
+		name:        "only junk tags",
+		tags:        []string{"latest", "nightly"},
+		expect:      "",
+		expectSkips: []string{"latest", "nightly"},
+	}, {
+		name:        "with prefix",
+		tags:        []string{"backend/v1.0.0", "backend/v1.1.0", "backend/junk", "v2.0.0"},
+		format:      tagFormat{prefix: "backend/"},
+		expect:      "backend/v1.1.0",
+		expectSkips: []string{"backend/junk"},
+	}, {
+		name:   "with the other prefixes in the monorepo",
+		tags:   []string{"api/v1.2.0", "api-extra/v3.0.0", "api/extra/v2.0.0", "v4.0.0"},
+		format: tagFormat{prefix: "api/"},
+		expect: "api/v1.2.0",
+	}, {
+		name:   "without prefix in the monorepo",
+		tags:   []string{"v1.0.0", "api/v2.0.0", "web/v3.0.0"},
+		expect: "v1.0.0",
+	}, {
+		name:   "with prefix and suffix",
+		tags:   []string{"app@1.0.0-linux", "app@1.2.0-linux", "app@1.3.0", "v2.0.0"},
+		format: tagFormat{prefix: "app@", suffix: "-linux"},
+		expect: "app@1.2.0-linux",
+	}, {
+		name:   "mixed v-prefix",
+		tags:   []string{"v1.2.0", "1.10.0", "v1.9.0", "1.3.0"},
+		expect: "1.10.0",
+	}, {
+		name:   "mixed v-prefix with the latest prefixed",
+		tags:   []string{"1.2.0", "v1.10.0", "1.9.0", "v1.3.0"},
+		expect: "v1.10.0",
+	}, {
+		name:   "mixed v-prefix with prefix",
+		tags:   []string{"backend/1.2.0", "backend/v1.10.0", "backend/1.11.0", "v2.0.0"},
+		format: tagFormat{prefix: "backend/"},
+		expect: "backend/1.11.0",
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, skipped := latestSemver(tc.tags, tc.format)
+			if got != tc.expect {
+				t.Errorf("got: %s, expected: %s", got, tc.expect)
+			}
+			if !reflect.DeepEqual(skipped, tc.expectSkips) {
+				t.Errorf("skipped: %v, expected: %v", skipped, tc.expectSkips)
+			}
+		})
+	}
+}
+
+func TestNext_normalizeVPrefix(t *testing.T) {
+	testCases := []struct {
+		latest  string
+		vPrefix bool
+		expect  string
+	}{
+		{"1.10.0", true, "v1.10.1"},
+		{"v1.10.0", false, "1.10.1"},
+		{"v1.10.0", true, "v1.10.1"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.latest, func(t *testing.T) {
+			sv, err := newSemver(tc.latest)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sv.vPrefix = tc.vPrefix
+			if got := sv.Next(bumpPatch).Tag(); got != tc.expect {
+				t.Errorf("got: %s, expected: %s", got, tc.expect)
+			}
+		})
+	}
+}
+
+func TestBumpFromLabels(t *testing.T) {
+	labels := func(names ...string) []*github.Label {
+		var ls []*github.Label
+		for _, n := range names {
+			ls = append(ls, &github.Label{Name: github.String(n)})
+		}
+		return ls
+	}
+	testCases := []struct {
+		name   string
+		labels []*github.Label
+		expect string
+	}{
+		{"no labels", nil, ""},
+		{"minor", labels("tagpr", "tagpr:minor"), bumpMinor},
+		{"minor and major", labels("tagpr:minor", "tagpr/major"), bumpMajor},
+		{"major and minor", labels("tagpr:major", "tagpr:minor"), bumpMajor},
+		{"unrelated", labels("bug", "minor"), ""},
+		{"case-insensitive", labels("Tagpr:Minor", " tagpr/MAJOR "), bumpMajor},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := bumpFromLabels(tc.labels); got != tc.expect {
+				t.Errorf("got: %q, expected: %q", got, tc.expect)
+			}
+		})
+	}
+}
+
+func TestBumpFromTitles(t *testing.T) {
+	reg := regexp.MustCompile(`^\[(major|minor|patch)\]`)
+	testCases := []struct {
+		name   string
+		titles []string
+		expect string
+	}{
+		{"no titles", nil, ""},
+		{"patch", []string{"[patch] Fix typo", "Update docs"}, bumpPatch},
+		{"minor and major", []string{"[minor] Add feature", "[major] Drop support", "[patch] Fix"}, bumpMajor},
+		{"patch and minor", []string{"[patch] Fix", "[minor] Add feature"}, bumpMinor},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := bumpFromTitles(reg, tc.titles); got != tc.expect {
+				t.Errorf("got: %q, expected: %q", got, tc.expect)
+			}
+		})
+	}
+}
+
+func TestResolveBump(t *testing.T) {
+	testCases := []struct {
+		name         string
+		sources      []bumpSource
+		expect       string
+		expectSource string
+	}{{
+		name:         "no sources",
+		expect:       bumpPatch,
+		expectSource: "default",
+	}, {
+		name:         "no bumps",
+		sources:      []bumpSource{{"labels", ""}, {"titles", ""}},
+		expect:       bumpPatch,
+		expectSource: "default",
+	}, {
+		name:         "labels take precedence over titles",
+		sources:      []bumpSource{{"labels", bumpMinor}, {"titles", bumpMajor}},
+		expect:       bumpMinor,
+		expectSource: "labels",
+	}, {
+		name:         "titles without labels",
+		sources:      []bumpSource{{"labels", ""}, {"titles", bumpMajor}},
+		expect:       bumpMajor,
+		expectSource: "titles",
+	}}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			bump, source := resolveBump(tc.sources...)
+			if bump != tc.expect || source != tc.expectSource {
+				t.Errorf("got: %s from %s, expected: %s from %s", bump, source, tc.expect, tc.expectSource)
+			}
+		})
+	}
+}
+
+func TestRecentSemvers(t *testing.T) {
+	tags := []string{"v1.0.0", "v1.10.0", "v1.2.0", "v2.0.0-rc.1", "api/v3.0.0", "v1.9.0", "junk"}
+	got := recentSemvers(tags, tagFormat{}, 3, "v1.10.0")
+	expect := []string{"v1.9.0", "v1.2.0", "v1.0.0"}
+	if !reflect.DeepEqual(got, expect) {
+		t.Errorf("got: %v, expect: %v", got, expect)
+	}
+	if got := recentSemvers(tags, tagFormat{}, 0, ""); got != nil {
+		t.Errorf("got: %v, expect: nil", got)
+	}
+
+	section := recentReleasesSection(got[:2], "https://github.com/Songmu/tagpr/releases/tag/")
+	expectSection := "## Previous Releases\n" +
+		"* [v1.9.0](https://github.com/Songmu/tagpr/releases/tag/v1.9.0)\n" +
+		"* [v1.2.0](https://github.com/Songmu/tagpr/releases/tag/v1.2.0)\n"
+	if section != expectSection {
+		t.Errorf("got:\n%s\nexpect:\n%s", section, expectSection)
+	}
+	if recentReleasesSection(nil, "") != "" {
+		t.Error("the section should be empty without the tags")
+	}
+}
+
+func TestCheckRegression(t *testing.T) {
+	testCases := []struct {
+		next, latest string
+		format       tagFormat
+		regression   bool
+	}{
+		{"v1.3.0", "v1.2.0", tagFormat{}, false},
+		{"v1.3.0", "", tagFormat{}, false},
+		{"v1.2.0", "v1.2.0", tagFormat{}, true},
+		{"v1.1.9", "v1.2.0", tagFormat{}, true},
+		{"v0.9.0", "api/v1.0.0", tagFormat{prefix: "api/"}, true},
+		{"v1.0.1", "api/v1.0.0", tagFormat{prefix: "api/"}, false},
+		// the candidate of tagpr.tagReleaseOnly
+		{"v1.2.0", "v1.2.0-rc", tagFormat{}, true},
+		{"v1.2.1", "v1.2.0-rc", tagFormat{}, false},
+	}
+	for _, tc := range testCases {
+		next, err := newSemver(tc.next)
+		if err != nil {
+			t.Fatal(err)
+		}
+		next.format = tc.format
+		err = checkRegression(next, tc.latest)
+		if g := errors.Is(err, ErrVersionRegression); g != tc.regression {
+			t.Errorf("checkRegression(%s, %q): got: %v, expect regression: %t", next.Tag(), tc.latest, err, tc.regression)
+		}
+	}
+}
+
+func TestNewerCandidate(t *testing.T) {
+	testCases := []struct {
+		name   string
+		tags   []string
+		latest string
+		expect string
+	}{
+		{"not promoted", []string{"v1.1.0", "v1.2.0-rc"}, "v1.1.0", "v1.2.0-rc"},
+		{"promoted", []string{"v1.1.0", "v1.2.0-rc", "v1.2.0"}, "v1.2.0", "v1.2.0"},
+		{"no final tags", []string{"v0.0.1-rc"}, "", "v0.0.1-rc"},
+		{"the other pre-releases", []string{"v1.1.0", "v1.2.0-rc.1", "v1.2.0-beta"}, "v1.1.0", "v1.1.0"},
+		{"the latest candidate", []string{"v1.1.0", "v1.2.0-rc", "v1.3.0-rc"}, "v1.1.0", "v1.3.0-rc"},
+	}
+	for _, tc := range testCases {
+		if got := newerCandidate(tc.tags, tagFormat{}, tc.latest); got != tc.expect {
+			t.Errorf("%s: got: %s, expect: %s", tc.name, got, tc.expect)
+		}
+	}
+}
@@ -2,7 +2,15 @@ package tagpr
 
 import (
 	"context"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
 	"strings"
+	"time"
 
 	"github.com/google/go-github/v47/github"
 )
@@ -24,12 +32,27 @@ func (tp *tagpr) latestPullRequest(ctx context.Context) (*github.PullRequest, er
 	return pulls[0], nil
 }
 
-func (tp *tagpr) tagRelease(ctx context.Context, pr *github.PullRequest, currVer *semv, latestSemverTag string) error {
+// tagRelease tags the merge commit of the release pull request on the branch, into which the
+// pull request is merged, and creates the release. The merge is usually HEAD of the branch.
+func (tp *tagpr) tagRelease(ctx context.Context, pr *github.PullRequest, sha string, currVer *semv,
+	latestSemverTag, branch string) error {
 	var (
 		vfile string
 		err   error
 	)
-	releaseBranch := tp.cfg.releaseBranch.String()
+	head, _, err := tp.c.Git("rev-parse", "HEAD")
+	if err != nil {
+		return err
+	}
+	// The merge is no longer HEAD if other commits land after it, e.g. in the freeze window,
+	// so it is checked out to be tagged, and the branch is checked out again after tagging.
+	ref := branch
+	if sha != head {
+		if _, _, err := tp.c.Git("checkout", "--detach", sha); err != nil {
+			return err
+		}
+		ref = sha
+	}
 
 	// Using "HEAD~" to retrieve the one previous commit before merging does not work well in cases
 	// "Rebase and merge" was used. However, we don't care about "Rebase and merge" and only support
@@ -42,24 +65,50 @@ func (tp *tagpr) tagRelease(ctx context.Context, pr *github.PullRequest, currVer
 		if err != nil {
 			return err
 		}
-		if _, _, err := tp.c.Git("checkout", releaseBranch); err != nil {
+		if _, _, err := tp.c.Git("checkout", ref); err != nil {
 			return err
 		}
 	} else {
-		vfiles := strings.Split(tp.cfg.versionFile.String(), ",")
-		vfile = strings.TrimSpace(vfiles[0])
+		vfiles, err := tp.versionFiles()
+		if err != nil {
+			return err
+		}
+		if len(vfiles) > 0 {
+			vfile = vfiles[0]
+		}
 	}
 
-	var nextTag string
+	var nextVer *semv
 	if vfile != "" {
-		nextVer, err := retrieveVersionFromFile(vfile, currVer.vPrefix)
+		nextVer, err = retrieveVersionFromFile(vfile, currVer.vPrefix, tp.cfg.NormalizeVersion())
 		if err != nil {
 			return err
 		}
-		nextTag = nextVer.Tag()
+		nextVer.format = currVer.format
 	} else {
-		nextTag = currVer.GuessNext(pr.Labels).Tag()
+		nextVer, err = tp.guessNext(ctx, currVer, pr, latestSemverTag)
+		if err != nil {
+			return err
+		}
+	}
+	if err := checkRegression(nextVer, latestSemverTag); err != nil && !tp.forced(err) {
+		return err
+	}
+	// The merge is tagged as the candidate with tagpr.tagReleaseOnly, which is promoted later.
+	tagVer := nextVer
+	if tp.cfg.TagReleaseOnly() {
+		if tagVer, err = nextVer.WithSuffix(candidateSuffix); err != nil {
+			return err
+		}
 	}
+	if tp.versionOnly {
+		tp.result = result{nextVersion: tagVer, pullRequest: pr}
+		if ref != branch {
+			_, _, err = tp.c.Git("checkout", branch)
+		}
+		return err
+	}
+	nextTag := tagVer.Tag()
 	previousTag := &latestSemverTag
 	if *previousTag == "" {
 		previousTag = nil
@@ -73,36 +122,618 @@ func (tp *tagpr) tagRelease(ctx context.Context, pr *github.PullRequest, currVer
 	if err != nil {
 		return nil
 	}
+	releases, err := tp.releaseNotes(ctx, nextTag, previousTag, targetCommitish, currVer, "")
+	if err != nil {
+		return err
+	}
+	if cf := tp.cfg.ChecksumsFile(); cf != "" {
+		section, err := checksumsSection(cf)
+		if err != nil {
+			return err
+		}
+		if section != "" {
+			releases.Body = insertBeforeFullChangelog(releases.Body, section)
+		}
+	}
+
+	// The tag already exists at HEAD if the previous run failed after tagging.
+	tagged, err := tp.isTaggedHEAD(nextTag)
+	if err != nil {
+		return err
+	}
+	if tagged {
+		log.Printf("the tag %s already exists at HEAD, so skip tagging\n", nextTag)
+	} else {
+		if _, _, err := tp.c.Git(tp.tagArgs(pr, nextTag)...); err != nil {
+			return err
+		}
+	}
+	_, _, err = tp.gitPush("--tags")
+	if err != nil {
+		return err
+	}
+	if err := tp.verifyRemoteTag(nextTag); err != nil {
+		return err
+	}
+	if ref != branch {
+		if _, _, err := tp.c.Git("checkout", branch); err != nil {
+			return err
+		}
+	}
+
+	tp.result = result{outcome: outcomeTagged, nextVersion: tagVer, pullRequest: pr}
+
+	rel, err := tp.releaseByTag(ctx, nextTag)
+	if err != nil {
+		return err
+	}
+	if rel != nil {
+		log.Printf("the release of %s already exists, so skip creating it\n", nextTag)
+	} else {
+		// Don't use GenerateReleaseNote flag and use pre generated one
+		rel, _, err = tp.gh.Repositories.CreateRelease(
+			ctx, tp.owner, tp.repo, &github.RepositoryRelease{
+				TagName:         &nextTag,
+				TargetCommitish: &branch,
+				Name:            &releases.Name,
+				Body:            &releases.Body,
+				Prerelease:      github.Bool(tp.cfg.TagReleaseOnly()),
+				// I want to make it as a draft release by default, but it is difficult to get a draft release
+				// from another tool via API, and there is no tool supports it, so I will make it as a normal
+				// release. In the future, there may be an option to create it as a Draft, or conversely,
+				// an option not to create a release.
+				// Draft: github.Bool(true),
+			})
+		if err != nil {
+			return err
+		}
+	}
+	// The assets uploaded by the previous run are skipped on resuming it.
+	if patterns := tp.cfg.ReleaseAssets(); len(patterns) > 0 {
+		if err := tp.uploadReleaseAssets(ctx, rel.GetID(), patterns); err != nil {
+			return err
+		}
+	}
+
+	if tp.cfg.Milestone() == milestoneAuto {
+		if err := tp.closeMilestone(ctx, nextVer.Tag()); err != nil {
+			return err
+		}
+	}
+	if tp.cfg.DeleteBranchAfterMerge() {
+		if err := tp.deleteHeadBranch(ctx, pr); err != nil {
+			return err
+		}
+	}
+	if suffix := tp.cfg.NextDevSuffix(); suffix != "" {
+		if err := tp.bumpNextDev(nextVer, suffix, vfile, branch); err != nil {
+			return err
+		}
+	}
+	// push to the mirrors at last not to block the release by their failures
+	return tp.pushTagToMirrors(nextTag)
+}
+
+// releaseNotes generates the release notes of the tag from the previous tag, and processes them
+// with the configurations, e.g. the sort, the label prefixes and the highlights. The previous
+// releases of tagpr.recentReleasesCount are picked from the tags listed by semverTags with merged.
+func (tp *tagpr) releaseNotes(ctx context.Context, tag string, previousTag *string, targetCommitish string,
+	currVer *semv, merged string) (*github.RepositoryReleaseNotes, error) {
 	releases, _, err := tp.gh.Repositories.GenerateReleaseNotes(
 		ctx, tp.owner, tp.repo, &github.GenerateNotesOptions{
-			TagName:         nextTag,
+			TagName:         tag,
 			PreviousTagName: previousTag,
 			TargetCommitish: &targetCommitish,
 		})
+	if err != nil {
+		return nil, err
+	}
+	by, err := tp.cfg.NotesSort()
+	if err != nil {
+		return nil, err
+	}
+	if by != "" {
+		if releases.Body, err = sortNotes(releases.Body, by, tp.pullMergedAt(ctx)); err != nil {
+			return nil, err
+		}
+	}
+	lps, err := tp.cfg.LabelPrefixes()
+	if err != nil {
+		return nil, err
+	}
+	if len(lps) > 0 {
+		if releases.Body, err = applyLabelPrefixes(releases.Body, lps, tp.pullLabels(ctx)); err != nil {
+			return nil, err
+		}
+	}
+
+	breakings, err := breakingChanges(releases.Body, tp.cfg.BreakingLabels(), tp.pullLabels(ctx))
+	if err != nil {
+		return nil, err
+	}
+	threshold, err := tp.cfg.HighlightReactions()
+	if err != nil {
+		return nil, err
+	}
+	if threshold > 0 {
+		reactions, err := reactionCounts(releases.Body, tp.pullReactions(ctx))
+		if err != nil {
+			return nil, err
+		}
+		releases.Body = insertHighlights(releases.Body, highlights(releases.Body, reactions, threshold), "* ")
+	}
+	releases.Body = insertBreakingChanges(releases.Body, breakings, "* ")
+	if tp.cfg.ShowClosedIssues() {
+		if releases.Body, err = appendClosedIssues(releases.Body, tp.pullClosedIssues(ctx)); err != nil {
+			return nil, err
+		}
+	}
+
+	n, err := tp.cfg.RecentReleasesCount()
+	if err != nil {
+		return nil, err
+	}
+	if n > 0 {
+		tags, err := tp.semverTags(currVer.format, merged)
+		if err != nil {
+			return nil, err
+		}
+		urlBase := fmt.Sprintf("https://%s/%s/%s/releases/tag/", tp.host, tp.owner, tp.repo)
+		if section := recentReleasesSection(recentSemvers(tags, currVer.format, n, tag), urlBase); section != "" {
+			releases.Body = strings.TrimSpace(releases.Body) + "\n\n" + section
+		}
+	}
+	return releases, nil
+}
+
+// deleteHeadBranch deletes the head branch of the merged release pull request.
+// The branch already deleted, e.g. by the "Automatically delete head branches" setting, is ignored.
+func (tp *tagpr) deleteHeadBranch(ctx context.Context, pr *github.PullRequest) error {
+	ref := pr.GetHead().GetRef()
+	if ref == "" || pr.GetHead().GetRepo().GetFullName() != tp.owner+"/"+tp.repo {
+		return nil
+	}
+	resp, err := tp.gh.Git.DeleteRef(ctx, tp.owner, tp.repo, "heads/"+ref)
+	if err != nil {
+		// GitHub responds 422 "Reference does not exist" for the branch already deleted
+		if resp != nil && (resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusUnprocessableEntity) {
+			log.Printf("the branch %q is already deleted\n", ref)
+			return nil
+		}
+		return err
+	}
+	log.Printf("deleted the branch %q of the release pull request #%d\n", ref, pr.GetNumber())
+	return nil
+}
+
+// releaseByTag returns the GitHub release of the tag, or nil if there is no release for it
+func (tp *tagpr) releaseByTag(ctx context.Context, tag string) (*github.RepositoryRelease, error) {
+	rel, resp, err := tp.gh.Repositories.GetReleaseByTag(ctx, tp.owner, tp.repo, tag)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return rel, nil
+}
+
+// tagArgs returns the arguments of git tag for the merged release pull request followed by the
+// args. The tag is annotated by the body of the pull request with tagpr.tagMessageFromPRBody.
+func (tp *tagpr) tagArgs(pr *github.PullRequest, args ...string) []string {
+	if tp.cfg.TagMessageFromPRBody() && pr.GetBody() != "" {
+		msg := strings.NewReplacer(bodyStartMarker+"\n", "", bodyEndMarker, "").Replace(pr.GetBody())
+		return append([]string{"tag", "-a", "-m", strings.TrimSpace(msg)}, args...)
+	}
+	return append([]string{"tag"}, args...)
+}
+
+// tagExisting re-creates the tag of the version at the merge commit of the merged release
+// pull request for it, to recover from the deleted tag. Neither the version is recomputed
+// nor the pull request is opened. The version can also be specified by the tag name.
+func (tp *tagpr) tagExisting(ctx context.Context, version string, currVer *semv) error {
+	if naked, ok := currVer.format.parse(version); ok {
+		version = naked
+	}
+	v, err := newSemver(version)
+	if err != nil {
+		return fmt.Errorf("%w: %s: %s", ErrInvalidConfig, configTagExisting, err)
+	}
+	tag := currVer.derive(v.v).Tag()
+	if tp.versionOnly {
+		tp.result = result{nextVersion: currVer.derive(v.v)}
+		return nil
+	}
+	if out, _, _ := tp.c.Git("ls-remote", tp.remoteName, "refs/tags/"+tag); out != "" {
+		return tp.noopErr(reasonAlreadyTagged, fmt.Errorf("%w: the tag %s already exists on the remote", ErrNoChanges, tag))
+	}
+	pr, err := tp.mergedReleasePull(ctx, tag, currVer.format)
+	if err != nil {
+		return err
+	}
+	sha := pr.GetMergeCommitSHA()
+	log.Printf("re-create the tag %s at %s merged by #%d\n", tag, sha, pr.GetNumber())
+	// The object of the commit may not be fetched in the shallow clone.
+	if _, _, err := tp.c.Git("cat-file", "-e", sha+"^{commit}"); err != nil {
+		if _, _, err := tp.c.Git("fetch", tp.remoteName, sha); err != nil {
+			return err
+		}
+	}
+	if _, _, err := tp.c.Git(tp.tagArgs(pr, "-f", tag, sha)...); err != nil {
+		return err
+	}
+	if _, _, err := tp.gitPush(tp.remoteName, "refs/tags/"+tag); err != nil {
+		return err
+	}
+	if err := tp.verifyRemoteTag(tag); err != nil {
+		return err
+	}
+	tp.result = result{outcome: outcomeTagged, nextVersion: currVer.derive(v.v), pullRequest: pr}
+	return nil
+}
+
+// regenerateRelease recomputes the release notes of the released version from the range of the
+// tags and updates the body of the GitHub release of it, to fix the notes after the fact, e.g.
+// after relabeling the pull requests. The version can also be specified by the tag name.
+func (tp *tagpr) regenerateRelease(ctx context.Context, version string) error {
+	currVer, _, err := tp.currentVersion()
+	if err != nil {
+		return err
+	}
+	if tp.cfg.vPrefix != nil {
+		currVer.vPrefix = *tp.cfg.vPrefix
+	}
+	if tp.cfg.TagTemplate() != "" {
+		currVer.vPrefix = false
+	}
+	if naked, ok := currVer.format.parse(version); ok {
+		version = naked
+	}
+	v, err := newSemver(version)
+	if err != nil {
+		return fmt.Errorf("invalid version to regenerate: %w", err)
+	}
+	nextVer := currVer.derive(v.v)
+	tag := nextVer.Tag()
+	if _, _, err := tp.c.Git("rev-parse", "--verify", "refs/tags/"+tag); err != nil {
+		return fmt.Errorf("the tag %s is not found in the local repository: %w", tag, err)
+	}
+	rel, err := tp.releaseByTag(ctx, tag)
 	if err != nil {
 		return err
 	}
+	if rel == nil {
+		return fmt.Errorf("the GitHub release of %s is not found, so nothing to regenerate", tag)
+	}
+	// the previous tag is the latest one reachable from the tag
+	tags, err := tp.semverTags(currVer.format, tag, "--no-contains", tag)
+	if err != nil {
+		return err
+	}
+	var previousTag *string
+	if prev, _ := latestSemver(tags, currVer.format); prev != "" {
+		previousTag = &prev
+	}
+	// The tag is at the merge of the release pull request, so the notes are generated until
+	// the commit before it as well as tagging.
+	targetCommitish, _, err := tp.c.Git("rev-parse", tag+"~")
+	if err != nil {
+		return err
+	}
+	releases, err := tp.releaseNotes(ctx, tag, previousTag, targetCommitish, currVer, tag)
+	if err != nil {
+		return err
+	}
+	if _, _, err := tp.gh.Repositories.EditRelease(ctx, tp.owner, tp.repo, rel.GetID(),
+		&github.RepositoryRelease{Body: &releases.Body}); err != nil {
+		return err
+	}
+	log.Printf("regenerated the release notes of %s\n", tag)
+	tp.result = result{outcome: outcomeUpdated, nextVersion: nextVer}
+	return nil
+}
 
-	if _, _, err := tp.c.Git("tag", nextTag); err != nil {
+// promoteCandidate tags the final version at the commit of the candidate tag, created on the merge
+// of the release pull request with tagpr.tagReleaseOnly, and creates the release of it. The release
+// of the candidate is left as it is as the pre-release.
+func (tp *tagpr) promoteCandidate(ctx context.Context, version string) error {
+	currVer, _, err := tp.currentVersion()
+	if err != nil {
 		return err
 	}
-	_, _, err = tp.c.Git("push", "--tags")
+	if tp.cfg.vPrefix != nil {
+		currVer.vPrefix = *tp.cfg.vPrefix
+	}
+	if tp.cfg.TagTemplate() != "" {
+		currVer.vPrefix = false
+	}
+	if naked, ok := currVer.format.parse(version); ok {
+		version = naked
+	}
+	v, err := newSemver(version)
+	if err != nil {
+		return fmt.Errorf("invalid version to promote: %w", err)
+	}
+	finalVer := currVer.derive(v.v)
+	candVer, err := finalVer.WithSuffix(candidateSuffix)
+	if err != nil {
+		return fmt.Errorf("invalid version to promote: %w", err)
+	}
+	tag, candidate := finalVer.Tag(), candVer.Tag()
+	sha, _, err := tp.c.Git("rev-parse", "--verify", "refs/tags/"+candidate+"^{commit}")
 	if err != nil {
+		return fmt.Errorf("the candidate tag %s is not found in the local repository: %w", candidate, err)
+	}
+	if out, _, _ := tp.c.Git("ls-remote", tp.remoteName, "refs/tags/"+tag); out != "" {
+		return tp.noopErr(reasonAlreadyTagged, fmt.Errorf("%w: the tag %s already exists on the remote", ErrNoChanges, tag))
+	}
+	// the release pull request is only for the message of the annotated tag
+	var pr *github.PullRequest
+	if tp.cfg.TagMessageFromPRBody() {
+		if pr, err = tp.mergedReleasePull(ctx, tag, currVer.format); err != nil {
+			return err
+		}
+	}
+	log.Printf("promote the candidate %s to %s at %s\n", candidate, tag, sha)
+	if _, _, err := tp.c.Git(tp.tagArgs(pr, "-f", tag, sha)...); err != nil {
+		return err
+	}
+	if _, _, err := tp.gitPush(tp.remoteName, "refs/tags/"+tag); err != nil {
+		return err
+	}
+	if err := tp.verifyRemoteTag(tag); err != nil {
 		return err
 	}
+	tp.result = result{outcome: outcomeTagged, nextVersion: finalVer, pullRequest: pr}
 
-	// Don't use GenerateReleaseNote flag and use pre generated one
-	_, _, err = tp.gh.Repositories.CreateRelease(
-		ctx, tp.owner, tp.repo, &github.RepositoryRelease{
-			TagName:         &nextTag,
-			TargetCommitish: &releaseBranch,
-			Name:            &releases.Name,
-			Body:            &releases.Body,
-			// I want to make it as a draft release by default, but it is difficult to get a draft release
-			// from another tool via API, and there is no tool supports it, so I will make it as a normal
-			// release. In the future, there may be an option to create it as a Draft, or conversely,
-			// an option not to create a release.
-			// Draft: github.Bool(true),
-		})
+	// the previous tag is the latest final one reachable from the candidate
+	tags, err := tp.semverTags(currVer.format, candidate, "--no-contains", candidate)
+	if err != nil {
+		return err
+	}
+	var previousTag *string
+	if prev, _ := latestSemver(tags, currVer.format); prev != "" {
+		previousTag = &prev
+	}
+	rel, err := tp.releaseByTag(ctx, tag)
+	if err != nil || rel != nil {
+		return err
+	}
+	targetCommitish, _, err := tp.c.Git("rev-parse", sha+"~")
+	if err != nil {
+		return err
+	}
+	releases, err := tp.releaseNotes(ctx, tag, previousTag, targetCommitish, currVer, candidate)
+	if err != nil {
+		return err
+	}
+	_, _, err = tp.gh.Repositories.CreateRelease(ctx, tp.owner, tp.repo, &github.RepositoryRelease{
+		TagName:         &tag,
+		TargetCommitish: &sha,
+		Name:            &releases.Name,
+		Body:            &releases.Body,
+	})
+	return err
+}
+
+// mergedReleasePull finds the merged release pull request for the tag. The pull request is
+// identified by the next version recorded in its body, or by the tag in its title.
+func (tp *tagpr) mergedReleasePull(ctx context.Context, tag string, tf tagFormat) (*github.PullRequest, error) {
+	titleReg := regexp.MustCompile(`(?:^|\s)` + regexp.QuoteMeta(tag) + `(?:\s|$)`)
+	opt := &github.PullRequestListOptions{
+		State:       "closed",
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+	for {
+		pulls, resp, err := tp.gh.PullRequests.List(ctx, tp.owner, tp.repo, opt)
+		if err != nil {
+			return nil, err
+		}
+		for _, pr := range pulls {
+			if pr.MergedAt == nil || !isTagPR(pr, tf) {
+				continue
+			}
+			if st := parseRunState(pr.GetBody()); st != nil && st.next == tag {
+				return pr, nil
+			}
+			if titleReg.MatchString(pr.GetTitle()) {
+				return pr, nil
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+	return nil, fmt.Errorf("the merged release pull request for %s is not found", tag)
+}
+
+// verifyRemoteTag checks the tag on the remote points at the same object as the local one,
+// to notice that the tag is clobbered by a concurrent push.
+func (tp *tagpr) verifyRemoteTag(tag string) error {
+	ref := "refs/tags/" + tag
+	local, _, err := tp.c.Git("rev-parse", ref)
+	if err != nil {
+		return err
+	}
+	out, _, err := tp.c.Git("ls-remote", tp.remoteName, ref)
+	if err != nil {
+		return err
+	}
+	var remote string
+	for _, line := range strings.Split(out, "\n") {
+		if fields := strings.Fields(line); len(fields) == 2 && fields[1] == ref {
+			remote = fields[0]
+			break
+		}
+	}
+	if remote != local {
+		return fmt.Errorf("%w: the tag %s on the remote points at %q, but expected %s",
+			ErrTagMismatch, tag, remote, local)
+	}
+	return nil
+}
+
+// pushTagToMirrors pushes the tag to tagpr.additionalRemotes. It tries all the remotes
+// even if some of them fail, and returns an error listing the failed ones.
+// TAGPR_PUSH_TOKEN is not used for them, as they may be on other hosts.
+func (tp *tagpr) pushTagToMirrors(tag string) error {
+	var failed []string
+	for _, remote := range tp.cfg.AdditionalRemotes() {
+		if _, _, err := tp.c.Git("push", remote, "refs/tags/"+tag); err != nil {
+			log.Printf("failed to push the tag %s to the remote %q: %s\n", tag, remote, err)
+			failed = append(failed, remote)
+			continue
+		}
+		log.Printf("pushed the tag %s to the remote %q\n", tag, remote)
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to push the tag %s to the remotes: %s", tag, strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// uploadReleaseAssets uploads the files matched with the glob patterns to the release
+func (tp *tagpr) uploadReleaseAssets(ctx context.Context, releaseID int64, patterns []string) error {
+	files, err := tp.missingReleaseAssets(ctx, releaseID, patterns)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		if err := tp.uploadReleaseAsset(ctx, releaseID, f); err != nil {
+			return fmt.Errorf("failed to upload the release asset %s: %w", f, err)
+		}
+	}
+	return nil
+}
+
+// missingReleaseAssets returns the files matched with the patterns which are not uploaded to
+// the release yet. The assets are identified by the file names.
+func (tp *tagpr) missingReleaseAssets(ctx context.Context, releaseID int64, patterns []string) ([]string, error) {
+	uploaded := map[string]bool{}
+	opt := &github.ListOptions{PerPage: 100}
+	for {
+		assets, resp, err := tp.gh.Repositories.ListReleaseAssets(ctx, tp.owner, tp.repo, releaseID, opt)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range assets {
+			uploaded[a.GetName()] = true
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+	var files []string
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %s", ErrInvalidConfig, configReleaseAssets, err)
+		}
+		if len(matches) == 0 {
+			log.Printf("no release assets are matched with %q\n", pattern)
+		}
+		for _, f := range matches {
+			if uploaded[filepath.Base(f)] {
+				log.Printf("the release asset %s is already uploaded, so skip it\n", filepath.Base(f))
+				continue
+			}
+			files = append(files, f)
+		}
+	}
+	return files, nil
+}
+
+const uploadRetries = 3
+
+func (tp *tagpr) uploadReleaseAsset(ctx context.Context, releaseID int64, fpath string) error {
+	mediaType := mime.TypeByExtension(filepath.Ext(fpath))
+	if mediaType == "" {
+		mediaType = "application/octet-stream"
+	}
+	var err error
+	for i := 0; i < uploadRetries; i++ {
+		if i > 0 {
+			log.Printf("retry uploading %s: %s\n", fpath, err)
+			time.Sleep(time.Duration(i) * time.Second)
+		}
+		var file *os.File
+		file, err = os.Open(fpath)
+		if err != nil {
+			return err
+		}
+		_, _, err = tp.gh.Repositories.UploadReleaseAsset(ctx, tp.owner, tp.repo, releaseID,
+			&github.UploadOptions{Name: filepath.Base(fpath), MediaType: mediaType}, file)
+		file.Close()
+		if err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// recentReleasesSection returns the "Previous Releases" section linking the releases of the tags
+func recentReleasesSection(tags []string, urlBase string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+	section := "## Previous Releases\n"
+	for _, tag := range tags {
+		section += fmt.Sprintf("* [%s](%s%s)\n", tag, urlBase, tag)
+	}
+	return section
+}
+
+// checksumsSection returns the "Checksums" section of the release notes with the content
+// of the checksums file, e.g. the output of sha256sum. If the file doesn't exist, it
+// returns an empty string with a warning.
+func checksumsSection(fpath string) (string, error) {
+	bs, err := os.ReadFile(fpath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			log.Printf("the checksums file %q is not found, so skip embedding it\n", fpath)
+			return "", nil
+		}
+		return "", err
+	}
+	content := strings.TrimSpace(string(bs))
+	if content == "" {
+		return "", nil
+	}
+	return "### Checksums\n```\n" + content + "\n```\n", nil
+}
+
+// bumpNextDev bumps the version files to the next development version after the release,
+// and pushes it to the release branch directly.
+func (tp *tagpr) bumpNextDev(releasedVer *semv, suffix, detectedVfile, releaseBranch string) error {
+	devVer, err := releasedVer.DevVersion(suffix)
+	if err != nil {
+		return fmt.Errorf("%w: %s: %s", ErrInvalidConfig, configNextDevSuffix, err)
+	}
+	var vfiles []string
+	if tp.cfg.VersionFile() != nil {
+		if vfiles, err = tp.versionFiles(); err != nil {
+			return err
+		}
+	} else if detectedVfile != "" {
+		vfiles = []string{detectedVfile}
+	}
+	if len(vfiles) == 0 {
+		return nil
+	}
+	txn := newFileTxn(tp.cfg.NormalizeVersion())
+	for _, vfile := range vfiles {
+		if _, err := txn.bumpVersionFile(vfile, releasedVer, devVer); err != nil {
+			return err
+		}
+	}
+	if err := txn.commit(); err != nil {
+		return err
+	}
+	if _, _, err := tp.c.Git("commit", "-am", autoNextDevMessage); err != nil {
+		return err
+	}
+	_, _, err = tp.gitPush(tp.remoteName, releaseBranch)
 	return err
 }
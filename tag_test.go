@@ -0,0 +1,151 @@
+package tagpr
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestRegenerateRelease(t *testing.T) {
+	r := newTestRepo(t, "[tagpr]\n\treleaseBranch = main\n\tversionFile = version.txt\n\tvPrefix = true\n"+
+		"\trunOnlyOnBranch = main\n")
+	fake := newFakeGitHub(t, r)
+	r.release(fake, "a.txt")
+	r.release(fake, "b.txt")
+	if tags := r.remoteGit("tag"); tags != "v0.0.1\nv0.0.2" {
+		t.Fatalf("unexpected tags: %s", tags)
+	}
+
+	// regenerate on a feature branch with a dirty worktree, which the other runs refuse
+	r.git("fetch", "--tags", "origin")
+	r.git("checkout", "-b", "feature", "origin/main")
+	r.write("a.txt", "dirty")
+	conf, err := os.ReadFile(".tagpr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake.notes = func(tag, prev string) string {
+		return "## What's Changed\n* Relabeled by @Songmu in https://github.com/Songmu/tagpr-test/pull/100\n\n" +
+			"**Full Changelog**: https://github.com/Songmu/tagpr-test/compare/" + prev + "..." + tag
+	}
+	tp, err := newTagPR(context.Background(), &commander{
+		gitPath: "git", outStream: io.Discard, errStream: io.Discard, dir: "."}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tp.gh = fake.client()
+	tp.regenerate = "0.0.2"
+	if err := tp.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if tp.result.outcome != outcomeUpdated || tp.result.nextVersion.Tag() != "v0.0.2" {
+		t.Errorf("unexpected result: %+v", tp.result)
+	}
+	body := fake.releases[1].GetBody()
+	if !strings.Contains(body, "Relabeled") || !strings.Contains(body, "compare/v0.0.1...v0.0.2") {
+		t.Errorf("the release notes of v0.0.2 should be regenerated from v0.0.1: %s", body)
+	}
+	if strings.Contains(fake.releases[0].GetBody(), "Relabeled") {
+		t.Errorf("the other release should not be changed: %s", fake.releases[0].GetBody())
+	}
+	if after, _ := os.ReadFile(".tagpr"); string(after) != string(conf) {
+		t.Errorf("the config file should not be changed:\n%s", after)
+	}
+
+	tp.regenerate = "v9.9.9"
+	if err := tp.Run(context.Background()); err == nil {
+		t.Error("error should be returned for the unknown tag")
+	}
+}
+
+func TestTagExisting(t *testing.T) {
+	r := newTestRepo(t, "[tagpr]\n\treleaseBranch = main\n\tversionFile = version.txt\n\tvPrefix = true\n"+
+		"\ttagMessageFromPRBody = true\n")
+	fake := newFakeGitHub(t, r)
+	r.release(fake, "a.txt")
+	fake.mu.Lock()
+	merged := fake.pulls[0].GetMergeCommitSHA()
+	fake.mu.Unlock()
+
+	// the deleted tag is re-created at the merge of the release pull request
+	r.git("tag", "-d", "v0.0.1")
+	r.remoteGit("tag", "-d", "v0.0.1")
+	t.Setenv(envTagExisting, "v0.0.1")
+	tp, err := r.runTagPR(fake, "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tp.result.outcome != outcomeTagged || tp.result.nextVersion.Tag() != "v0.0.1" {
+		t.Errorf("unexpected result: %+v", tp.result)
+	}
+	if sha := r.remoteGit("rev-parse", "v0.0.1^{commit}"); sha != merged {
+		t.Errorf("got: %s, expected the merge commit: %s", sha, merged)
+	}
+	// annotated by the body of the pull request as well as tagging on the merge
+	if typ := r.remoteGit("cat-file", "-t", "v0.0.1"); typ != "tag" {
+		t.Errorf("the tag should be annotated, but got: %s", typ)
+	}
+	if msg := r.remoteGit("tag", "-l", "--format=%(contents)", "v0.0.1"); !strings.Contains(msg, "for the next release as v0.0.1") {
+		t.Errorf("the tag message should be the body of the pull request: %s", msg)
+	}
+
+	_, err = r.runTagPR(fake, "main")
+	if !errors.Is(err, ErrNoChanges) {
+		t.Errorf("the existing tag should not be re-created: %v", err)
+	}
+}
+
+func TestPromoteCandidate(t *testing.T) {
+	r := newTestRepo(t, "[tagpr]\n\treleaseBranch = main\n\tversionFile = version.txt\n\tvPrefix = true\n"+
+		"\ttagReleaseOnly = true\n")
+	fake := newFakeGitHub(t, r)
+	tp := r.release(fake, "a.txt")
+	if tp.result.outcome != outcomeTagged || tp.result.nextVersion.Tag() != "v0.0.1-rc" {
+		t.Errorf("the candidate should be tagged: %+v", tp.result)
+	}
+	if tags := r.remoteGit("tag"); tags != "v0.0.1-rc" {
+		t.Fatalf("only the candidate should be tagged: %s", tags)
+	}
+	if len(fake.releases) != 1 || !fake.releases[0].GetPrerelease() {
+		t.Errorf("the release of the candidate should be the pre-release: %v", fake.releases)
+	}
+
+	// the candidate is in the version sequence, so the same version is not released again
+	tp, err := r.runTagPR(fake, "main")
+	if !errors.Is(err, ErrNoChanges) || tp.result.reason != reasonAlreadyTagged {
+		t.Errorf("the candidate should be done: %v, %+v", err, tp.result)
+	}
+	r.pushChange("b.txt")
+	if tp, err = r.runTagPR(fake, "main"); err != nil {
+		t.Fatal(err)
+	}
+	if tp.result.outcome != outcomeCreated || tp.result.nextVersion.Tag() != "v0.0.2" {
+		t.Errorf("the release pull request for v0.0.2 should be created: %+v", tp.result)
+	}
+
+	promote := func() (*tagpr, error) {
+		tp := r.newTagPR(fake, "main")
+		tp.promote = "v0.0.1"
+		return tp, tp.Run(context.Background())
+	}
+	if tp, err = promote(); err != nil {
+		t.Fatal(err)
+	}
+	if tp.result.outcome != outcomeTagged || tp.result.nextVersion.Tag() != "v0.0.1" {
+		t.Errorf("v0.0.1 should be tagged: %+v", tp.result)
+	}
+	if final, cand := r.remoteGit("rev-parse", "v0.0.1^{commit}"), r.remoteGit("rev-parse", "v0.0.1-rc^{commit}"); final != cand {
+		t.Errorf("the final tag should be at the candidate: got: %s, expected: %s", final, cand)
+	}
+	if len(fake.releases) != 2 || fake.releases[1].GetTagName() != "v0.0.1" || fake.releases[1].GetPrerelease() {
+		t.Errorf("the release of v0.0.1 should be created: %v", fake.releases)
+	}
+
+	// promoted already
+	if tp, err = promote(); !errors.Is(err, ErrNoChanges) || tp.result.reason != reasonAlreadyTagged {
+		t.Errorf("v0.0.1 should be promoted already: %v, %+v", err, tp.result)
+	}
+}
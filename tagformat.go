@@ -0,0 +1,71 @@
+package tagpr
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// tagFormat is the format of tags as "<prefix><version><suffix>". e.g. "app@1.2.3"
+type tagFormat struct {
+	prefix, suffix string
+}
+
+// placeholder to split the rendered tag template into the prefix and the suffix
+const tagVersionPlaceholder = "\x00"
+
+// newTagFormat builds the tag format from the template like "release-v{{.Version}}".
+// The template must contain {{.Version}} exactly once to parse versions back from tags.
+func newTagFormat(tmplStr string) (tagFormat, error) {
+	tmpl, err := template.New("tag template").Parse(tmplStr)
+	if err != nil {
+		return tagFormat{}, err
+	}
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, struct{ Version string }{tagVersionPlaceholder}); err != nil {
+		return tagFormat{}, err
+	}
+	parts := strings.Split(b.String(), tagVersionPlaceholder)
+	if len(parts) != 2 {
+		return tagFormat{}, fmt.Errorf("tag template must contain {{.Version}} exactly once: %q", tmplStr)
+	}
+	return tagFormat{prefix: parts[0], suffix: parts[1]}, nil
+}
+
+// renderBranchName renders the branch name of the release pull request with the template
+// like "release/{{.Version}}". The field .Version is the tag name of the next version.
+func renderBranchName(tmplStr string, next *semv) (string, error) {
+	tmpl, err := template.New("branch template").Parse(tmplStr)
+	if err != nil {
+		return "", err
+	}
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, struct{ Version string }{next.Tag()}); err != nil {
+		return "", err
+	}
+	name := strings.TrimSpace(b.String())
+	if name == "" || strings.ContainsAny(name, " \t\n~^:?*[\\") {
+		return "", fmt.Errorf("invalid branch name %q rendered from %q", name, tmplStr)
+	}
+	return name, nil
+}
+
+func (tf tagFormat) name(ver string) string {
+	return tf.prefix + ver + tf.suffix
+}
+
+// parse returns the version part of the tag and whether the tag matches the format.
+// The version part can't contain "/", so that the tags of the other prefixes in the monorepo
+// like "api-extra/v1.0.0" for "api" or "api/v1.0.0" for no prefix don't match the format.
+func (tf tagFormat) parse(tag string) (string, bool) {
+	if len(tag) <= len(tf.prefix)+len(tf.suffix) ||
+		!strings.HasPrefix(tag, tf.prefix) || !strings.HasSuffix(tag, tf.suffix) {
+		return "", false
+	}
+	ver := tag[len(tf.prefix) : len(tag)-len(tf.suffix)]
+	if strings.Contains(ver, "/") {
+		return "", false
+	}
+	return ver, true
+}
@@ -0,0 +1,91 @@
+package tagpr
+
+import "testing"
+
+func TestTagFormat(t *testing.T) {
+	testCases := []struct {
+		tmpl   string
+		ver    string
+		expect string
+	}{
+		{"{{.Version}}", "1.2.3", "1.2.3"},
+		{"release-v{{.Version}}", "1.2.3", "release-v1.2.3"},
+		{"app@{{.Version}}", "1.2.3", "app@1.2.3"},
+		{"{{.Version}}-linux", "1.2.3", "1.2.3-linux"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.tmpl, func(t *testing.T) {
+			tf, err := newTagFormat(tc.tmpl)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tag := tf.name(tc.ver)
+			if tag != tc.expect {
+				t.Errorf("got: %s, expected: %s", tag, tc.expect)
+			}
+			ver, ok := tf.parse(tag)
+			if !ok || ver != tc.ver {
+				t.Errorf("round trip failed: %s, %t", ver, ok)
+			}
+		})
+	}
+}
+
+func TestTagFormat_invalid(t *testing.T) {
+	for _, tmpl := range []string{"release", "{{.Version}}-{{.Version}}", "{{.Version"} {
+		if _, err := newTagFormat(tmpl); err == nil {
+			t.Errorf("error should be occurred for %q", tmpl)
+		}
+	}
+}
+
+func TestTagFormat_parse(t *testing.T) {
+	tf := tagFormat{prefix: "app@", suffix: "-linux"}
+	for _, tag := range []string{"app@-linux", "web@1.2.3-linux", "app@1.2.3", "1.2.3-linux"} {
+		if ver, ok := tf.parse(tag); ok {
+			t.Errorf("%q should not match, but: %s", tag, ver)
+		}
+	}
+}
+
+func TestTagFormat_parseOtherPrefixes(t *testing.T) {
+	testCases := []struct {
+		prefix string
+		tags   []string
+	}{
+		{"api", []string{"api-extra/v1.0.0", "api/v1.0.0"}},
+		{"api/", []string{"api-extra/v1.0.0", "api/extra/v1.0.0", "apiv1.0.0"}},
+		{"", []string{"api/v1.0.0", "api-extra/v1.0.0"}},
+	}
+	for _, tc := range testCases {
+		tf := tagFormat{prefix: tc.prefix}
+		for _, tag := range tc.tags {
+			if ver, ok := tf.parse(tag); ok {
+				t.Errorf("%q should not match the prefix %q, but: %s", tag, tc.prefix, ver)
+			}
+		}
+	}
+	if ver, ok := (tagFormat{prefix: "api/"}).parse("api/v1.0.0"); !ok || ver != "v1.0.0" {
+		t.Errorf("api/v1.0.0 should match the prefix api/: %s, %t", ver, ok)
+	}
+}
+
+func TestRenderBranchName(t *testing.T) {
+	next, err := newSemver("v1.3.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	next.format = tagFormat{prefix: "api/"}
+	got, err := renderBranchName("release/{{.Version}}", next)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := "release/api/v1.3.0"; got != expect {
+		t.Errorf("got: %s, expect: %s", got, expect)
+	}
+	for _, tmpl := range []string{"", "release {{.Version}}", "{{.Version", "release/{{.Nothing}}"} {
+		if got, err := renderBranchName(tmpl, next); err == nil {
+			t.Errorf("error should be occurred for %q, but got: %s", tmpl, got)
+		}
+	}
+}
@@ -7,14 +7,14 @@ import (
 	"log"
 	"net/url"
 	"os"
+	"os/exec"
+	"path"
 	"path/filepath"
 	"regexp"
 	"strings"
-	"text/template"
 	"time"
 
 	"github.com/Songmu/gh2changelog"
-	"github.com/Songmu/gitsemvers"
 	"github.com/google/go-github/v47/github"
 )
 
@@ -24,6 +24,8 @@ const (
 	defaultReleaseBranch = "main"
 	autoCommitMessage    = "[tagpr] prepare for the next release"
 	autoChangelogMessage = "[tagpr] update CHANGELOG.md"
+	autoNextDevMessage   = "[tagpr] prepare for the next development"
+	snapshotSuffix       = "-SNAPSHOT"
 	autoLableName        = "tagpr"
 	branchPrefix         = "tagpr-from-"
 )
@@ -34,96 +36,358 @@ type tagpr struct {
 	cfg                     *config
 	gitPath                 string
 	remoteName, owner, repo string
+	host                    string
+	// force bypasses the safety checks
+	force bool
+	// regenerate is the version to regenerate the release notes by "tagpr regenerate"
+	regenerate string
+	// promote is the version to promote the candidate tag to the final one by "tagpr promote"
+	promote string
+	// versionOnly only computes the next version for --version-out without making any changes,
+	// that is, neither the push nor the write by the GitHub API
+	versionOnly bool
+	// maintenance reports whether the release branch is the maintenance branch, resolved by
+	// onMaintenanceBranch in advance not to detect the default branch on every listing of the tags
+	maintenance bool
+
+	result result
 }
 
-func (tp *tagpr) latestSemverTag() string {
-	vers := (&gitsemvers.Semvers{GitPath: tp.gitPath}).VersionStrings()
-	if len(vers) > 0 {
-		return vers[0]
+// outcome represents what the run of the tagpr did
+type outcome int
+
+const (
+	outcomeNoop outcome = iota
+	outcomeCreated
+	outcomeUpdated
+	outcomeTagged
+)
+
+func (o outcome) String() string {
+	switch o {
+	case outcomeCreated:
+		return "created"
+	case outcomeUpdated:
+		return "updated"
+	case outcomeTagged:
+		return "tagged"
+	default:
+		return "noop"
 	}
-	return ""
 }
 
-func newTagPR(ctx context.Context, c *commander) (*tagpr, error) {
-	tp := &tagpr{c: c, gitPath: c.gitPath}
+// the reasons why there is nothing to release
+const (
+	reasonNotRunBranch  = "not_run_branch"
+	reasonAlreadyTagged = "already_tagged"
+	reasonNoNewChanges  = "no_new_changes"
+	reasonUpToDate      = "up_to_date"
+	reasonFrozen        = "frozen"
+	reasonVetoed        = "vetoed"
+	reasonAllExcluded   = "all_excluded"
+)
+
+type result struct {
+	outcome     outcome
+	nextVersion *semv
+	pullRequest *github.PullRequest
+	// reason and message explain why there is nothing to release
+	reason  string
+	message string
+}
+
+// noop records the reason why there is nothing to release in the result and logs it.
+func (tp *tagpr) noop(reason, message string) {
+	tp.result.outcome = outcomeNoop
+	tp.result.reason, tp.result.message = reason, message
+	log.Printf("nothing to release (%s): %s\n", reason, message)
+}
+
+// noopErr records the reason in the result as well as noop, but returns the error
+// (ErrNoChanges, ErrVetoed or ErrEmptyNotes) instead of logging it.
+func (tp *tagpr) noopErr(reason string, err error) error {
+	tp.result.outcome = outcomeNoop
+	tp.result.reason, tp.result.message = reason, err.Error()
+	return err
+}
+
+// semverTags lists the tags of the tag format. On the maintenance branch like "1.2.x", only the
+// tags reachable from HEAD are listed, so that the next version is derived from its own latest tag,
+// e.g. "v1.2.4" not "v2.0.0". The merged narrows them to the ones reachable from it instead of HEAD.
+func (tp *tagpr) semverTags(tf tagFormat, merged string, extraArgs ...string) ([]string, error) {
+	args := append([]string{"tag", "--list", tf.prefix + "*" + tf.suffix}, extraArgs...)
+	if merged == "" && tp.maintenance {
+		merged = "HEAD"
+	}
+	if merged != "" {
+		args = append(args, "--merged", merged)
+	}
+	out, _, err := tp.c.Git(args...)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(out), nil
+}
+
+func (tp *tagpr) latestSemverTag(extraArgs ...string) string {
+	tf, err := tp.cfg.TagFormat()
+	if err != nil {
+		return ""
+	}
+	tags, err := tp.semverTags(tf, "", extraArgs...)
+	if err != nil {
+		return ""
+	}
+	latest, skipped := latestSemver(tags, tf)
+	if len(skipped) > 0 {
+		log.Printf("skipped tags not parsed as semver: %s\n", strings.Join(skipped, ", "))
+	}
+	// The candidate not promoted yet is the latest one, not to release the same version again.
+	if tp.cfg.TagReleaseOnly() {
+		latest = newerCandidate(tags, tf, latest)
+	}
+	return latest
+}
+
+// onMaintenanceBranch reports whether the release branch is configured to the branch other
+// than the default branch of the repository, e.g. a hotfix branch for the older versions.
+// With tagpr.prBaseBranch, the releases are tagged on it, so it is compared instead.
+// The default branch is detected only if the release branch is configured, and the failure of
+// it is an error, not to derive the version from the latest tag of the other branches.
+func (tp *tagpr) onMaintenanceBranch() (bool, error) {
+	rb := tp.cfg.ReleaseBranch()
+	if b := tp.cfg.PRBaseBranch(); b != nil && !b.Empty() {
+		rb = b
+	}
+	if rb == nil || rb.Empty() {
+		return false, nil
+	}
+	def, err := tp.defaultBranch()
+	if err != nil {
+		return false, err
+	}
+	return def != rb.String(), nil
+}
+
+func newTagPR(ctx context.Context, c *commander, profile string) (*tagpr, error) {
+	// TAGPR_GIT_PATH is applied before reading the config file, as it is read by git itself.
+	if p := os.Getenv(envGitPath); p != "" {
+		c.gitPath = p
+	}
+	tp := &tagpr{c: c, gitPath: c.getGitPath()}
 
 	var err error
+	tp.cfg, err = newConfig(tp.gitPath, profile)
+	if err != nil {
+		return nil, err
+	}
+	// tagpr.gitPath in the config file is used for all the git operations after reading it
+	if p := tp.cfg.GitPath(); p != "" && p != tp.gitPath {
+		c.gitPath, tp.gitPath = p, p
+		tp.cfg.setGitPath(p)
+	}
 	tp.remoteName, err = tp.detectRemote()
 	if err != nil {
 		return nil, err
 	}
-	remoteURL, _, err := tp.c.Git("config", "remote."+tp.remoteName+".url")
+
+	// use ls-remote to get the URL with the url.<base>.insteadOf rewrites applied
+	remoteURL, _, err := tp.c.Git("ls-remote", "--get-url", tp.remoteName)
 	if err != nil {
 		return nil, err
 	}
-	u, err := parseGitURL(remoteURL)
+	tp.host, tp.owner, tp.repo, err = parseRemote(remoteURL)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse remote")
+		if tp.cfg.Owner() == "" || tp.cfg.Repo() == "" || tp.cfg.Host() == "" {
+			return nil, err
+		}
 	}
-	m := strings.Split(strings.TrimPrefix(u.Path, "/"), "/")
-	if len(m) < 2 {
-		return nil, fmt.Errorf("failed to detect owner and repo from remote URL")
+	if owner := tp.cfg.Owner(); owner != "" {
+		tp.owner = owner
+	}
+	if repo := tp.cfg.Repo(); repo != "" {
+		tp.repo = repo
 	}
-	tp.owner = m[0]
-	repo := m[1]
-	if u.Scheme == "ssh" || u.Scheme == "git" {
-		repo = strings.TrimSuffix(repo, ".git")
+	if host := tp.cfg.Host(); host != "" {
+		tp.host = host
 	}
-	tp.repo = repo
 
-	cli, err := ghClient(ctx, "", u.Hostname())
+	cli, err := ghClient(ctx, "", tp.host, tp.cfg.UserAgent())
 	if err != nil {
 		return nil, err
 	}
 	tp.gh = cli
 
-	isShallow, _, err := tp.c.Git("rev-parse", "--is-shallow-repository")
+	if tp.maintenance, err = tp.onMaintenanceBranch(); err != nil {
+		return nil, err
+	}
+
+	shallowFile, _, err := tp.c.Git("rev-parse", "--git-path", "shallow")
 	if err != nil {
 		return nil, err
 	}
-	if isShallow == "true" {
+	if exists(shallowFile) {
+		if !tp.cfg.AutoUnshallow() {
+			return nil, fmt.Errorf("the repository is a shallow clone, so tags and history can't be detected. "+
+				"Fetch all history (e.g. \"fetch-depth: 0\" for actions/checkout) or set %s=true", configAutoUnshallow)
+		}
 		if _, _, err := tp.c.Git("fetch", "--unshallow"); err != nil {
 			return nil, err
 		}
 	}
-	tp.cfg, err = newConfig(tp.gitPath)
-	if err != nil {
-		return nil, err
-	}
 	return tp, nil
 }
 
-func isTagPR(pr *github.PullRequest) bool {
-	if pr == nil || pr.Head == nil || pr.Head.Ref == nil || !strings.HasPrefix(*pr.Head.Ref, branchPrefix) {
+// isTagPR reports whether the pull request is the release one for the version sequence in the
+// tag format. It is identified by the marker label applied at the creation, and by the branch
+// name as the fallback, e.g. for the pull requests whose labels are removed by someone.
+func isTagPR(pr *github.PullRequest, tf tagFormat) bool {
+	if pr == nil {
 		return false
 	}
-	for _, label := range pr.Labels {
-		if label.GetName() == autoLableName {
+	isVersion := func(tag string) bool {
+		ver, ok := tf.parse(tag)
+		if !ok {
+			return false
+		}
+		_, err := newSemver(ver)
+		return err == nil
+	}
+	ref := pr.GetHead().GetRef()
+	if matchedLabel(pr.Labels, []string{autoLableName}) != "" {
+		// The version sequence must be the one in the tag format, for when multiple
+		// profiles are used in the repository.
+		if st := parseRunState(pr.GetBody()); st != nil {
+			return isVersion(st.next)
+		}
+		if !strings.HasPrefix(ref, branchPrefix) {
 			return true
 		}
 	}
-	return false
+	return strings.HasPrefix(ref, branchPrefix) && isVersion(strings.TrimPrefix(ref, branchPrefix))
 }
 
-func (tp *tagpr) Run(ctx context.Context) error {
+// checkChecklist returns an error listing the unchecked task items in the body of the pull request
+func checkChecklist(pr *github.PullRequest) error {
+	var unchecked []string
+	for _, item := range parseChecklist(pr.GetBody()) {
+		if !item.Checked {
+			unchecked = append(unchecked, "- "+item.Text)
+		}
+	}
+	if len(unchecked) > 0 {
+		return fmt.Errorf("the merged pull request #%d has the unchecked items, so refuse to tag:\n%s",
+			pr.GetNumber(), strings.Join(unchecked, "\n"))
+	}
+	return nil
+}
+
+// labelMatches reports whether the label matches the configured name. They are compared
+// case-insensitively after trimming the spaces, as the casing of the labels varies across
+// the repositories, e.g. "Minor" and "minor". The name can be a glob pattern of path.Match,
+// e.g. "kind/*", for the namespaced labels. The name is compared literally first, so the labels
+// with the meta characters like "[WIP]" match as they are. The invalid pattern matches nothing else.
+func labelMatches(label, name string) bool {
+	label, name = strings.ToLower(strings.TrimSpace(label)), strings.ToLower(strings.TrimSpace(name))
+	if label == name {
+		return true
+	}
+	if !strings.ContainsAny(name, "*?[") {
+		return false
+	}
+	ok, _ := path.Match(name, label)
+	return ok
+}
+
+// matchedLabel returns the name of the first label that matches one of the names.
+// It returns an empty string if nothing matched.
+func matchedLabel(labels []*github.Label, names []string) string {
+	for _, l := range labels {
+		for _, name := range names {
+			if labelMatches(l.GetName(), name) {
+				return l.GetName()
+			}
+		}
+	}
+	return ""
+}
+
+// currentVersion returns the version of the latest tag of the version sequence and the tag.
+// It is "v0.0.0" if there are no tags yet. The v-prefix of it is the one of the tag until
+// it is overridden by the config.
+func (tp *tagpr) currentVersion() (*semv, string, error) {
+	tf, err := tp.cfg.TagFormat()
+	if err != nil {
+		return nil, "", err
+	}
 	latestSemverTag := tp.latestSemverTag()
-	currVerStr := latestSemverTag
+	currVerStr, _ := tf.parse(latestSemverTag)
 	if currVerStr == "" {
 		currVerStr = "v0.0.0"
 	}
 	currVer, err := newSemver(currVerStr)
 	if err != nil {
-		return err
+		return nil, "", err
+	}
+	// the current version of the candidate of tagpr.tagReleaseOnly is the final one
+	if v, err := currVer.v.SetPrerelease(""); err == nil {
+		currVer.v = &v
+	}
+	currVer.format = tf
+	return currVer, latestSemverTag, nil
+}
+
+func (tp *tagpr) Run(ctx context.Context) error {
+	// "tagpr regenerate" only rewrites the GitHub release, so it runs on any branch and worktree
+	// without touching the config file.
+	if tp.regenerate != "" {
+		return tp.regenerateRelease(ctx, tp.regenerate)
+	}
+	// "tagpr promote" only tags the commit of the existing candidate and creates the release as well.
+	if tp.promote != "" {
+		return tp.promoteCandidate(ctx, tp.promote)
+	}
+	if b := tp.cfg.RunOnlyOnBranch(); b != "" {
+		// symbolic-ref fails on the detached HEAD, and it is also not the branch
+		current, _, _ := tp.c.Git("symbolic-ref", "--short", "HEAD")
+		if current != b {
+			tp.noop(reasonNotRunBranch, fmt.Sprintf("the current branch %q is not %q specified by %s",
+				current, b, configRunOnlyOnBranch))
+			return nil
+		}
+	}
+	if !tp.cfg.AllowDirtyWorktree() {
+		if err := tp.checkWorktree(); err != nil && !tp.forced(err) {
+			return err
+		}
 	}
 
+	currVer, latestSemverTag, err := tp.currentVersion()
+	if err != nil {
+		return err
+	}
 	if tp.cfg.vPrefix == nil {
-		if err := tp.cfg.SetVPrefix(currVer.vPrefix); err != nil {
-			return err
+		// Detect the convention from the latest release tag and persist it. The v-prefix is
+		// adopted if there are no release tags yet.
+		if latestSemverTag != "" {
+			log.Printf("%s is detected as %t from the latest tag %q\n", configVPrefix, currVer.vPrefix, latestSemverTag)
+		}
+		if !tp.versionOnly {
+			if err := tp.cfg.SetVPrefix(currVer.vPrefix); err != nil {
+				return err
+			}
 		}
 	} else {
 		currVer.vPrefix = *tp.cfg.vPrefix
 	}
+	// The tag template includes the v-prefix by itself if needed.
+	if tp.cfg.TagTemplate() != "" {
+		currVer.vPrefix = false
+	}
 
+	if v := tp.cfg.TagExisting(); v != "" {
+		return tp.tagExisting(ctx, v, currVer)
+	}
 	var releaseBranch string
 	if r := tp.cfg.ReleaseBranch(); r != nil {
 		releaseBranch = r.String()
@@ -133,8 +397,10 @@ func (tp *tagpr) Run(ctx context.Context) error {
 		if releaseBranch == "" {
 			releaseBranch = defaultReleaseBranch
 		}
-		if err := tp.cfg.SetRelaseBranch(releaseBranch); err != nil {
-			return err
+		if !tp.versionOnly {
+			if err := tp.cfg.SetRelaseBranch(releaseBranch); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -142,9 +408,76 @@ func (tp *tagpr) Run(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("failed to git symbolic-ref: %w", err)
 	}
-	if branch != releaseBranch {
-		return fmt.Errorf("you are not on release branch %q, current branch is %q",
-			releaseBranch, branch)
+	baseBranch := releaseBranch
+	if b := tp.cfg.PRBaseBranch(); b != nil && !b.Empty() {
+		baseBranch = b.String()
+	}
+	// The release pull request is merged into tagpr.prBaseBranch, so the tagpr also runs on it
+	// only to tag the merge.
+	if branch != releaseBranch && branch != baseBranch {
+		return fmt.Errorf("%w: you are not on release branch %q, current branch is %q",
+			ErrNoReleaseBranch, releaseBranch, branch)
+	}
+
+	if com := tp.cfg.Preflight(); com != "" && !tp.versionOnly {
+		if err := tp.preflight(ctx, com); err != nil {
+			if errors.Is(err, ErrVetoed) {
+				return tp.noopErr(reasonVetoed, err)
+			}
+			return err
+		}
+	}
+
+	if latestSemverTag != "" {
+		tagged, err := tp.isTaggedHEAD(latestSemverTag)
+		if err != nil {
+			return err
+		}
+		if tagged {
+			pr, err := tp.latestPullRequest(ctx)
+			if err != nil {
+				return err
+			}
+			done := !isTagPR(pr, currVer.format)
+			if !done && tp.cfg.NextDevSuffix() == "" {
+				// nothing is left if the release exists with all the assets, as HEAD isn't moved
+				// without the next development version
+				rel, err := tp.releaseByTag(ctx, latestSemverTag)
+				if err != nil {
+					return err
+				}
+				done = rel != nil
+				if patterns := tp.cfg.ReleaseAssets(); done && len(patterns) > 0 {
+					missing, err := tp.missingReleaseAssets(ctx, rel.GetID(), patterns)
+					if err != nil {
+						return err
+					}
+					done = len(missing) == 0
+				}
+			}
+			if done {
+				return tp.noopErr(reasonAlreadyTagged,
+					fmt.Errorf("%w: the latest tag %q already points at HEAD", ErrNoChanges, latestSemverTag))
+			}
+			// The previous run tagging the merge of the release pull request may have failed
+			// halfway, e.g. before creating the GitHub release or bumping the next development
+			// version. Resume the post-merge actions from the previous tag. They skip what is
+			// already done.
+			log.Printf("resume the release of %s tagged at the merge of the release pull request #%d\n",
+				latestSemverTag, pr.GetNumber())
+			latestSemverTag = tp.latestSemverTag("--no-contains", "HEAD")
+			prevVerStr, _ := currVer.format.parse(latestSemverTag)
+			if prevVerStr == "" {
+				prevVerStr = "v0.0.0"
+			}
+			prevVer, err := newSemver(prevVerStr)
+			if err != nil {
+				return err
+			}
+			// the candidate of tagpr.tagReleaseOnly is derived as the final version
+			final, _ := prevVer.v.SetPrerelease("")
+			currVer = currVer.derive(&final)
+		}
 	}
 
 	// XXX: should care GIT_*_NAME etc?
@@ -159,46 +492,123 @@ func (tp *tagpr) Run(ctx context.Context) error {
 		}
 	}
 
-	// If the latest commit is a merge commit of the pull request by tagpr,
+	// If the latest commit is a merge commit of the pull request by tagpr into the current branch,
 	// tag the semver to the commit and create a release and exit.
-	if pr, err := tp.latestPullRequest(ctx); err != nil || isTagPR(pr) {
+	if pr, err := tp.latestPullRequest(ctx); err != nil || isTagPR(pr, currVer.format) && pr.GetBase().GetRef() == branch {
+		if err != nil {
+			return err
+		}
+		sha, _, err := tp.c.Git("rev-parse", "HEAD")
+		if err != nil {
+			return err
+		}
+		if released, err := tp.releaseMerge(ctx, pr, sha, currVer, latestSemverTag, branch); released || err != nil {
+			return err
+		}
+	} else if pr, err := tp.untaggedTagPR(ctx, branch, currVer.format, latestSemverTag); err != nil || pr != nil {
+		// The merge is left untagged if other commits land after it before the run after the
+		// freeze window, so tag the merge commit instead of HEAD.
 		if err != nil {
 			return err
 		}
-		return tp.tagRelease(ctx, pr, currVer, latestSemverTag)
+		log.Printf("the merge of the release pull request #%d is not tagged yet\n", pr.GetNumber())
+		if released, err := tp.releaseMerge(ctx, pr, pr.GetMergeCommitSHA(), currVer, latestSemverTag, branch); released || err != nil {
+			return err
+		}
+	}
+	if branch != releaseBranch {
+		tp.noop(reasonNotRunBranch, fmt.Sprintf("the latest commit of %q specified by %s is not the merge of the release pull request",
+			branch, configPRBaseBranch))
+		return nil
 	}
 
-	rcBranch := fmt.Sprintf("%s%s", branchPrefix, currVer.Tag())
-	tp.c.Git("branch", "-D", rcBranch)
-	if _, _, err := tp.c.Git("checkout", "-b", rcBranch); err != nil {
-		return err
+	// Don't start another release cycle if only the files maintained by the tagpr
+	// are changed since the latest tag, e.g. by the next development version bump.
+	if latestSemverTag != "" {
+		only, err := tp.onlyReleaseFilesChanged(latestSemverTag, currVer)
+		if err != nil {
+			return err
+		}
+		if only {
+			return tp.noopErr(reasonNoNewChanges, fmt.Errorf(
+				"%w: only the version files and the changelog are changed since %q", ErrNoChanges, latestSemverTag))
+		}
 	}
 
-	head := fmt.Sprintf("%s:%s", tp.owner, rcBranch)
-	pulls, _, err := tp.gh.PullRequests.List(ctx, tp.owner, tp.repo,
-		&github.PullRequestListOptions{
-			Head: head,
-			Base: releaseBranch,
-		})
+	baseSHA, _, err := tp.c.Git("rev-parse", "HEAD")
+	if err != nil {
+		return err
+	}
+	rcBranch := fmt.Sprintf("%s%s", branchPrefix, currVer.Tag())
+	branchTmpl := tp.cfg.PRBranchTemplate()
+	var currTagPR *github.PullRequest
+	if branchTmpl == "" {
+		pulls, _, err := tp.gh.PullRequests.List(ctx, tp.owner, tp.repo,
+			&github.PullRequestListOptions{
+				Head: fmt.Sprintf("%s:%s", tp.owner, rcBranch),
+				Base: baseBranch,
+			})
+		if err != nil {
+			return err
+		}
+		if len(pulls) > 0 {
+			currTagPR = pulls[0]
+		}
+	} else {
+		// The branch name depends on the next version computed with the release pull request,
+		// so the pull request is looked up by the marker label instead of the branch name.
+		if currTagPR, err = tp.openTagPR(ctx, baseBranch, currVer.format); err != nil {
+			return err
+		}
+	}
+	nextVer, err := tp.guessNext(ctx, currVer, currTagPR, latestSemverTag)
 	if err != nil {
 		return err
 	}
+	if err := checkRegression(nextVer, latestSemverTag); err != nil && !tp.forced(err) {
+		return err
+	}
+	if tp.versionOnly {
+		tp.result = result{nextVersion: nextVer, pullRequest: currTagPR}
+		return nil
+	}
+	// superseded is the release pull request for the other version closed after the new one is opened
+	var superseded *github.PullRequest
+	if branchTmpl != "" {
+		if rcBranch, err = renderBranchName(branchTmpl, nextVer); err != nil {
+			return fmt.Errorf("%w: %s: %s", ErrInvalidConfig, configPRBranchTemplate, err)
+		}
+		// The head branch of the pull request can't be renamed, so open another one for the
+		// new branch name if the next version is changed, e.g. by the labels.
+		if currTagPR != nil && currTagPR.GetHead().GetRef() != rcBranch {
+			log.Printf("the next version is changed to %s, so open another release pull request for %q instead of #%d\n",
+				nextVer.Tag(), rcBranch, currTagPR.GetNumber())
+			superseded, currTagPR = currTagPR, nil
+		}
+	}
+	// Skip recomputing the release pull request if nothing is changed since the last run.
+	if currTagPR != nil {
+		st := parseRunState(currTagPR.GetBody())
+		if st != nil && *st == (runState{
+			prev: latestSemverTag, next: nextVer.Tag(), base: baseSHA, head: currTagPR.GetHead().GetSHA(),
+		}) {
+			tp.result = result{nextVersion: nextVer, pullRequest: currTagPR}
+			tp.noop(reasonUpToDate, fmt.Sprintf("the release pull request #%d is up to date", currTagPR.GetNumber()))
+			return nil
+		}
+	}
 
-	var (
-		labels    []*github.Label
-		currTagPR *github.PullRequest
-	)
-	if len(pulls) > 0 {
-		currTagPR = pulls[0]
-		labels = currTagPR.Labels
+	tp.c.Git("branch", "-D", rcBranch)
+	if _, _, err := tp.c.Git("checkout", "-b", rcBranch); err != nil {
+		return err
 	}
-	nextVer := currVer.GuessNext(labels)
+	head := fmt.Sprintf("%s:%s", tp.owner, rcBranch)
 
 	var vfiles []string
 	if vf := tp.cfg.VersionFile(); vf != nil {
-		vfiles = strings.Split(vf.String(), ",")
-		for i, v := range vfiles {
-			vfiles[i] = strings.TrimSpace(v)
+		vfiles, err = tp.versionFiles()
+		if err != nil {
+			return err
 		}
 	} else {
 		vfile, err := detectVersionFile(".", currVer)
@@ -208,26 +618,53 @@ func (tp *tagpr) Run(ctx context.Context) error {
 		if err := tp.cfg.SetVersionFile(vfile); err != nil {
 			return err
 		}
-		vfiles = []string{vfile}
+		if vfile != "" {
+			vfiles = []string{vfile}
+		}
 	}
 
+	var comOutput string
 	if com := tp.cfg.Command(); com != nil {
-		prog := com.String()
-		var progArgs []string
-		if strings.ContainsAny(prog, " \n") {
-			prog = "sh"
-			progArgs = []string{"-c", prog}
+		comOutput, err = tp.runCommand(ctx, com.String())
+		if err != nil {
+			return err
 		}
-		tp.c.Cmd(prog, progArgs...)
 	}
 
-	if vfiles[0] != "" {
-		for _, vfile := range vfiles {
-			if err := bumpVersionFile(vfile, currVer, nextVer); err != nil {
+	var devVer *semv
+	if suffix := tp.cfg.NextDevSuffix(); suffix != "" && latestSemverTag != "" {
+		devVer, err = currVer.DevVersion(suffix)
+		if err != nil {
+			return fmt.Errorf("%w: %s: %s", ErrInvalidConfig, configNextDevSuffix, err)
+		}
+	}
+	// bump all the version files at once not to leave some of them bumped on failure
+	txn := newFileTxn(tp.cfg.NormalizeVersion())
+	for _, vfile := range vfiles {
+		if fpath, _ := splitVersionFile(vfile); isChartYAML(fpath) {
+			if _, err := txn.bumpChartYAML(fpath, tp.cfg.ChartKeys(), nextVer); err != nil {
 				return err
 			}
+			continue
+		}
+		// The version files have the development version after the last release
+		// if tagpr.nextDevSuffix is specified.
+		if devVer != nil {
+			replaced, err := txn.bumpVersionFile(vfile, devVer, nextVer)
+			if err != nil {
+				return err
+			}
+			if replaced {
+				continue
+			}
+		}
+		if _, err := txn.bumpVersionFile(vfile, currVer, nextVer); err != nil {
+			return err
 		}
 	}
+	if err := txn.commit(); err != nil {
+		return err
+	}
 	tp.c.Git("add", "-f", tp.cfg.conf) // ignore any errors
 
 	const releaseYml = ".github/release.yml"
@@ -246,6 +683,17 @@ func (tp *tagpr) Run(ctx context.Context) error {
 		tp.c.Git("add", "-f", releaseYml)
 	}
 
+	var versionDiff string
+	if tp.cfg.ShowVersionDiff() && len(vfiles) > 0 {
+		args := []string{"diff", "--no-color", "HEAD", "--"}
+		for _, vfile := range vfiles {
+			fpath, _ := splitVersionFile(vfile)
+			args = append(args, fpath)
+		}
+		if versionDiff, _, err = tp.c.Git(args...); err != nil {
+			return err
+		}
+	}
 	if _, _, err := tp.c.Git("commit", "--allow-empty", "-am", autoCommitMessage); err != nil {
 		return err
 	}
@@ -287,17 +735,39 @@ func (tp *tagpr) Run(ctx context.Context) error {
 	// Reread the configuration file (.tagpr) as it may have been rewritten during the cherry-pick process.
 	tp.cfg.Reload()
 	if tp.cfg.VersionFile() != nil {
-		vfiles = strings.Split(tp.cfg.VersionFile().String(), ",")
-		for i, v := range vfiles {
-			vfiles[i] = strings.TrimSpace(v)
+		vfiles, err = tp.versionFiles()
+		if err != nil {
+			return err
 		}
 	}
-	if vfiles[0] != "" {
-		nVer, _ := retrieveVersionFromFile(vfiles[0], nextVer.vPrefix)
+	if len(vfiles) > 0 {
+		nVer, _ := retrieveVersionFromFile(vfiles[0], nextVer.vPrefix, tp.cfg.NormalizeVersion())
 		if nVer != nil && nVer.Naked() != nextVer.Naked() {
+			nVer.format = nextVer.format
 			nextVer = nVer
 		}
 	}
+	// Strip the "-SNAPSHOT" suffix of Maven convention from the XML version files like pom.xml
+	// for the release. It can be restored after the release by tagpr.nextDevSuffix.
+	if snapshotVer, err := nextVer.WithSuffix(snapshotSuffix); err == nil {
+		txn := newFileTxn(tp.cfg.NormalizeVersion())
+		for _, vfile := range vfiles {
+			if fpath, _ := splitVersionFile(vfile); !isXMLFile(fpath) {
+				continue
+			}
+			if _, err := txn.bumpVersionFile(vfile, snapshotVer, nextVer); err != nil {
+				return err
+			}
+		}
+		if err := txn.commit(); err != nil {
+			return err
+		}
+		for _, fpath := range txn.paths {
+			if _, _, err := tp.c.Git("add", fpath); err != nil {
+				return err
+			}
+		}
+	}
 
 	gch, err := gh2changelog.New(ctx,
 		gh2changelog.GitPath(tp.gitPath),
@@ -308,44 +778,152 @@ func (tp *tagpr) Run(ctx context.Context) error {
 		return err
 	}
 
-	changelogMd := "CHANGELOG.md"
+	nf, err := tp.cfg.NotesFormat()
+	if err != nil {
+		return err
+	}
+	changelogFile := nf.file()
 	changelog, orig, err := gch.Draft(ctx, nextVer.Tag(), time.Now())
 	if err != nil {
 		return err
 	}
-	if !exists(changelogMd) {
-		logs, _, err := gch.Changelogs(ctx, 20)
+	if tp.cfg.IncludeDirectCommits() && latestSemverTag != "" {
+		commits, err := tp.directCommits(ctx, latestSemverTag, releaseBranch)
+		if err != nil {
+			return err
+		}
+		if len(commits) > 0 {
+			section := "### Direct Commits\n* " + strings.Join(commits, "\n* ") + "\n"
+			// list items of CHANGELOG.md are "-" as converted by gh2changelog
+			changelog = strings.TrimSpace(changelog) + "\n\n" + strings.ReplaceAll(section, "\n* ", "\n- ")
+			orig = insertBeforeFullChangelog(orig, section)
+		}
+	}
+	if by, err := tp.cfg.NotesSort(); err != nil {
+		return err
+	} else if by != "" {
+		mergedAtOf := tp.pullMergedAt(ctx)
+		if changelog, err = sortNotes(changelog, by, mergedAtOf); err != nil {
+			return err
+		}
+		if orig, err = sortNotes(orig, by, mergedAtOf); err != nil {
+			return err
+		}
+	}
+	labelsOf := tp.pullLabels(ctx)
+	lps, err := tp.cfg.LabelPrefixes()
+	if err != nil {
+		return err
+	}
+	if len(lps) > 0 {
+		if changelog, err = applyLabelPrefixes(changelog, lps, labelsOf); err != nil {
+			return err
+		}
+		if orig, err = applyLabelPrefixes(orig, lps, labelsOf); err != nil {
+			return err
+		}
+	}
+	breakings, err := breakingChanges(orig, tp.cfg.BreakingLabels(), labelsOf)
+	if err != nil {
+		return err
+	}
+	threshold, err := tp.cfg.HighlightReactions()
+	if err != nil {
+		return err
+	}
+	var reactions map[int]int
+	if threshold > 0 {
+		if reactions, err = reactionCounts(orig, tp.pullReactions(ctx)); err != nil {
+			return err
+		}
+	}
+	highlighted := highlights(orig, reactions, threshold)
+	// list items of CHANGELOG.md are "-" as converted by gh2changelog
+	changelog = insertBreakingChanges(insertHighlights(changelog, highlighted, "- "), breakings, "- ")
+	orig = insertBreakingChanges(insertHighlights(orig, highlighted, "* "), breakings, "* ")
+	if tp.cfg.ShowClosedIssues() {
+		// the lines of the pull requests no longer end with the URL after this
+		issuesOf := tp.pullClosedIssues(ctx)
+		if changelog, err = appendClosedIssues(changelog, issuesOf); err != nil {
+			return err
+		}
+		if orig, err = appendClosedIssues(orig, issuesOf); err != nil {
+			return err
+		}
+	}
+	if tp.cfg.RequireNotes() && isEmptyNotes(changelog) {
+		err := fmt.Errorf("%w: no pull requests or commits are found for %s", ErrEmptyNotes, nextVer.Tag())
+		if !tp.forced(err) {
+			return tp.noopErr(reasonAllExcluded, err)
+		}
+	}
+	if lt := tp.cfg.ChangelogLinkTemplate(); lt != "" {
+		changelog, err = renderChangelogHeading(changelog, lt)
+		if err != nil {
+			return fmt.Errorf("%w: %s: %s", ErrInvalidConfig, configChangelogLinkTemplate, err)
+		}
+	}
+	var logs []string
+	if !exists(changelogFile) {
+		logs, _, err = gch.Changelogs(ctx, 20)
 		if err != nil {
 			return err
 		}
-		changelog = strings.Join(
-			append([]string{changelog}, logs...), "\n")
+		for i, l := range logs {
+			logs[i] = tp.withTaggerDate(l)
+		}
 	}
-	if _, err := gch.Update(changelog, 0); err != nil {
+	if err := nf.update(gch, changelogFile, append([]string{changelog}, logs...)); err != nil {
 		return err
 	}
 
-	tp.c.Git("add", changelogMd)
-	tp.c.Git("commit", "-m", autoChangelogMessage)
+	tp.c.Git("add", changelogFile)
+	// Amend the release commit only if it is still the HEAD, that is, no commits were
+	// cherry-picked onto it, to avoid rewriting commits added by someone else.
+	subject, _, _ := tp.c.Git("log", "-1", "--format=%s")
+	if tp.cfg.AmendReleaseCommit() && subject == autoCommitMessage {
+		tp.c.Git("commit", "--amend", "--no-edit")
+	} else {
+		tp.c.Git("commit", "-m", autoChangelogMessage)
+	}
 
-	if _, _, err := tp.c.Git("push", "--force", tp.remoteName, rcBranch); err != nil {
+	if _, _, err := tp.gitPush("--force", tp.remoteName, rcBranch); err != nil {
 		return err
 	}
 
-	var tmpl *template.Template
+	engine, err := tp.cfg.PRTemplateEngine()
+	if err != nil {
+		return err
+	}
+	var tmpl executor
 	if t := tp.cfg.Template(); t != nil {
-		tmpTmpl, err := template.ParseFiles(t.String())
+		tmpTmpl, err := parsePRTmpl(t.String(), engine)
 		if err == nil {
 			tmpl = tmpTmpl
 		} else {
 			log.Printf("parse configured template failed: %s\n", err)
 		}
 	}
-	pt := newPRTmpl(tmpl)
-	prText, err := pt.Render(&tmplArg{
-		NextVersion: nextVer.Tag(),
-		Branch:      rcBranch,
-		Changelog:   orig,
+	titles, err := tp.mergedTitles(latestSemverTag)
+	if err != nil {
+		return err
+	}
+	// The edits by maintainers, e.g. the checklist and the next version, are carried over to
+	// the new pull request from the superseded one.
+	prevBody := currTagPR.GetBody()
+	if superseded != nil {
+		prevBody = superseded.GetBody()
+	}
+	pt := newPRTmpl(tmpl, engine)
+	prText, err := pt.Render(&tmplArg{
+		NextVersion:     nextVer.Tag(),
+		Branch:          rcBranch,
+		Changelog:       orig,
+		Scopes:          groupByScope(titles),
+		BreakingChanges: breakings,
+		Highlights:      highlighted,
+		Reactions:       reactions,
+		Checklist:       parseChecklist(prevBody),
 	})
 	if err != nil {
 		return err
@@ -357,24 +935,762 @@ func (tp *tagpr) Run(ctx context.Context) error {
 	if len(stuffs) > 1 {
 		body = strings.TrimSpace(stuffs[1])
 	}
+	var details string
+	if versionDiff != "" {
+		details += fmt.Sprintf(
+			"\n\n<details>\n<summary>Changes of the version files</summary>\n\n```diff\n%s\n```\n</details>", versionDiff)
+	}
+	if tp.cfg.IncludeCommandOutput() && comOutput != "" {
+		details += fmt.Sprintf(
+			"\n\n<details>\n<summary>Output of the command</summary>\n\n```\n%s\n```\n</details>", comOutput)
+	}
+	// the details are posted as the comment instead with tagpr.prComment
+	if !tp.cfg.PRComment() {
+		body += details
+	}
+	if com := tp.cfg.BodyCommand(); com != "" {
+		if body, err = tp.transformBody(ctx, com, body); err != nil {
+			return err
+		}
+	}
+	headSHA, _, err := tp.c.Git("rev-parse", "HEAD")
+	if err != nil {
+		return err
+	}
+	state := runState{prev: latestSemverTag, next: nextVer.Tag(), base: baseSHA, head: headSHA}.String()
+	if mm := tp.cfg.MergeMethod(); mm != "" {
+		// hint for integrations merging the pull request
+		state += fmt.Sprintf("\n<!-- tagpr:merge-method %s -->", mm)
+	}
+	body += "\n" + state
+	var pr *github.PullRequest
 	if currTagPR == nil {
-		pr, _, err := tp.gh.PullRequests.Create(ctx, tp.owner, tp.repo, &github.NewPullRequest{
+		pr, _, err = tp.gh.PullRequests.Create(ctx, tp.owner, tp.repo, &github.NewPullRequest{
 			Title: github.String(title),
-			Body:  github.String(body),
-			Base:  &releaseBranch,
+			Body:  github.String(keepNextVersion(prevBody, mergeBody(prevBody, body))),
+			Base:  &baseBranch,
 			Head:  github.String(head),
 		})
 		if err != nil {
 			return err
 		}
-		_, _, err = tp.gh.Issues.AddLabelsToIssue(
-			ctx, tp.owner, tp.repo, *pr.Number, []string{autoLableName})
+		tp.result = result{outcome: outcomeCreated, nextVersion: nextVer, pullRequest: pr}
+		labels := []string{autoLableName}
+		// Carry over the labels like "tagpr:minor" as well, or the next version computed
+		// without them changes the branch name back on the next run.
+		if superseded != nil {
+			for _, l := range superseded.Labels {
+				if !labelMatches(l.GetName(), autoLableName) {
+					labels = append(labels, l.GetName())
+				}
+			}
+		}
+		if _, _, err := tp.gh.Issues.AddLabelsToIssue(
+			ctx, tp.owner, tp.repo, *pr.Number, labels); err != nil {
+			return err
+		}
+	} else {
+		currTagPR.Title = github.String(title)
+		currTagPR.Body = github.String(keepNextVersion(currTagPR.GetBody(), mergeBody(currTagPR.GetBody(), body)))
+		pr, _, err = tp.gh.PullRequests.Edit(ctx, tp.owner, tp.repo, *currTagPR.Number, currTagPR)
+		if err == nil && matchedLabel(currTagPR.Labels, []string{autoLableName}) == "" {
+			// restore the marker label to detect the merge of the release pull request
+			_, _, err = tp.gh.Issues.AddLabelsToIssue(
+				ctx, tp.owner, tp.repo, *currTagPR.Number, []string{autoLableName})
+		}
+		if err != nil {
+			return err
+		}
+		tp.result = result{outcome: outcomeUpdated, nextVersion: nextVer, pullRequest: pr}
+	}
+
+	if tp.cfg.PRComment() {
+		if err := tp.upsertPRComment(ctx, pr.GetNumber(), prCommentBody(latestSemverTag, nextVer.Tag(), details)); err != nil {
+			return err
+		}
+	}
+
+	if superseded != nil {
+		if err := tp.closeSuperseded(ctx, superseded, pr); err != nil {
+			return err
+		}
+		if err := tp.deleteHeadBranch(ctx, superseded); err != nil {
+			return err
+		}
+	}
+
+	if tp.cfg.PRAutoCloseStale() {
+		if err := tp.closeStalePulls(ctx, pr, baseBranch, currVer.format); err != nil {
+			return err
+		}
+	}
+
+	if tp.cfg.Milestone() == milestoneAuto {
+		included, err := tp.mergedPullNumbers(latestSemverTag, releaseBranch)
+		if err != nil {
+			return err
+		}
+		if err := tp.attachMilestone(ctx, nextVer.Tag(), pr, included); err != nil {
+			return err
+		}
+	}
+
+	if tp.cfg.AutoMerge() {
+		mm := tp.cfg.MergeMethod()
+		if mm == "" {
+			mm = mergeMethodMerge
+		}
+		return enableAutoMerge(ctx, tp.gh, tp.host, pr.GetNodeID(), mm)
+	}
+	return nil
+}
+
+// closeStalePulls closes the open release pull requests other than the current one with
+// a comment, as they are superseded, e.g. after a direct hotfix release.
+func (tp *tagpr) closeStalePulls(ctx context.Context, current *github.PullRequest, baseBranch string, tf tagFormat) error {
+	pulls, _, err := tp.gh.PullRequests.List(ctx, tp.owner, tp.repo, &github.PullRequestListOptions{
+		State:       "open",
+		Base:        baseBranch,
+		ListOptions: github.ListOptions{PerPage: 100},
+	})
+	if err != nil {
 		return err
 	}
-	currTagPR.Title = github.String(title)
-	currTagPR.Body = github.String(mergeBody(*currTagPR.Body, body))
-	_, _, err = tp.gh.PullRequests.Edit(ctx, tp.owner, tp.repo, *currTagPR.Number, currTagPR)
-	return err
+	for _, pr := range pulls {
+		if pr.GetNumber() == current.GetNumber() || !isTagPR(pr, tf) {
+			continue
+		}
+		if err := tp.closeSuperseded(ctx, pr, current); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// closeSuperseded closes the release pull request superseded by the current one with a comment
+func (tp *tagpr) closeSuperseded(ctx context.Context, pr, current *github.PullRequest) error {
+	if _, _, err := tp.gh.Issues.CreateComment(ctx, tp.owner, tp.repo, pr.GetNumber(), &github.IssueComment{
+		Body: github.String(fmt.Sprintf("Superseded by #%d.", current.GetNumber())),
+	}); err != nil {
+		return err
+	}
+	if _, _, err := tp.gh.PullRequests.Edit(ctx, tp.owner, tp.repo, pr.GetNumber(), &github.PullRequest{
+		State: github.String("closed"),
+	}); err != nil {
+		return err
+	}
+	log.Printf("closed the stale release pull request #%d\n", pr.GetNumber())
+	return nil
+}
+
+// openTagPR finds the open release pull request of the version sequence for the base branch
+func (tp *tagpr) openTagPR(ctx context.Context, baseBranch string, tf tagFormat) (*github.PullRequest, error) {
+	opt := &github.PullRequestListOptions{
+		State:       "open",
+		Base:        baseBranch,
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+	for {
+		pulls, resp, err := tp.gh.PullRequests.List(ctx, tp.owner, tp.repo, opt)
+		if err != nil {
+			return nil, err
+		}
+		for _, pr := range pulls {
+			if matchedLabel(pr.Labels, []string{autoLableName}) != "" && isTagPR(pr, tf) {
+				return pr, nil
+			}
+		}
+		if resp.NextPage == 0 {
+			return nil, nil
+		}
+		opt.Page = resp.NextPage
+	}
+}
+
+// runState is the state of the run recorded in the body of the release pull request,
+// to skip recomputing it on the next run if neither the release branch, the release pull
+// request branch, the latest tag nor the next version are changed.
+type runState struct {
+	prev, next, base, head string
+}
+
+const runStateMarker = "<!-- tagpr:state "
+
+func (st runState) String() string {
+	return fmt.Sprintf("%sprev=%s next=%s base=%s head=%s -->", runStateMarker, st.prev, st.next, st.base, st.head)
+}
+
+var runStateReg = regexp.MustCompile(regexp.QuoteMeta(runStateMarker) + `prev=(\S*) next=(\S+) base=(\S+) head=(\S+) -->`)
+
+// parseRunState returns the state recorded in the body, or nil if there is no state
+func parseRunState(body string) *runState {
+	m := runStateReg.FindStringSubmatch(body)
+	if m == nil {
+		return nil
+	}
+	return &runState{prev: m[1], next: m[2], base: m[3], head: m[4]}
+}
+
+// directCommits lists the commits pushed directly to the release branch without pull
+// requests since the tag, keyed by the commit subject and the author.
+func (tp *tagpr) directCommits(ctx context.Context, from, to string) ([]string, error) {
+	out, _, err := tp.c.Git("log", "--first-parent", "--no-merges",
+		"--format=%H%x00%h%x00%an%x00%s", from+".."+to)
+	if err != nil {
+		return nil, err
+	}
+	var commits []string
+	for _, line := range strings.Split(out, "\n") {
+		stuffs := strings.SplitN(line, "\x00", 4)
+		if len(stuffs) < 4 {
+			continue
+		}
+		sha, short, author, subject := stuffs[0], stuffs[1], stuffs[2], stuffs[3]
+		// ignore the commits by the tagpr itself
+		if strings.HasPrefix(subject, "[tagpr] ") {
+			continue
+		}
+		pulls, _, err := tp.gh.PullRequests.ListPullRequestsWithCommit(ctx, tp.owner, tp.repo, sha, nil)
+		if err != nil {
+			return nil, err
+		}
+		if len(pulls) == 0 {
+			commits = append(commits, fmt.Sprintf("%s (%s) by %s", subject, short, author))
+		}
+	}
+	return commits, nil
+}
+
+// pullLabels returns the function to retrieve the label names of the pull request with cache
+func (tp *tagpr) pullLabels(ctx context.Context) func(int) ([]string, error) {
+	cache := map[int][]string{}
+	return func(num int) ([]string, error) {
+		if names, ok := cache[num]; ok {
+			return names, nil
+		}
+		labels, _, err := tp.gh.Issues.ListLabelsByIssue(ctx, tp.owner, tp.repo, num, nil)
+		if err != nil {
+			return nil, err
+		}
+		var names []string
+		for _, l := range labels {
+			names = append(names, l.GetName())
+		}
+		cache[num] = names
+		return names, nil
+	}
+}
+
+// pullMergedAt returns the function to retrieve the merge time of the pull request with the cache.
+func (tp *tagpr) pullMergedAt(ctx context.Context) func(int) (time.Time, error) {
+	cache := map[int]time.Time{}
+	return func(num int) (time.Time, error) {
+		if t, ok := cache[num]; ok {
+			return t, nil
+		}
+		pr, _, err := tp.gh.PullRequests.Get(ctx, tp.owner, tp.repo, num)
+		if err != nil {
+			return time.Time{}, err
+		}
+		t := pr.GetMergedAt()
+		cache[num] = t
+		return t, nil
+	}
+}
+
+// pullClosedIssues returns the function to retrieve the links of the issues closed by the
+// pull request with the cache.
+func (tp *tagpr) pullClosedIssues(ctx context.Context) func(int) ([]string, error) {
+	cache := map[int][]string{}
+	return func(num int) ([]string, error) {
+		if links, ok := cache[num]; ok {
+			return links, nil
+		}
+		pr, _, err := tp.gh.PullRequests.Get(ctx, tp.owner, tp.repo, num)
+		if err != nil {
+			return nil, err
+		}
+		links := closedIssueLinks(pr.GetBody(), tp.host, tp.owner+"/"+tp.repo)
+		cache[num] = links
+		return links, nil
+	}
+}
+
+// pullReactions returns the function to retrieve the number of the :+1: reactions of the pull request with the cache.
+func (tp *tagpr) pullReactions(ctx context.Context) func(int) (int, error) {
+	cache := map[int]int{}
+	return func(num int) (int, error) {
+		if n, ok := cache[num]; ok {
+			return n, nil
+		}
+		issue, _, err := tp.gh.Issues.Get(ctx, tp.owner, tp.repo, num)
+		if err != nil {
+			return 0, err
+		}
+		n := issue.GetReactions().GetPlusOne()
+		cache[num] = n
+		return n, nil
+	}
+}
+
+// withTaggerDate replaces the date in the heading of the changelog section, which is
+// the commit date of the tag, with the tagger date if the tag is an annotated one.
+func (tp *tagpr) withTaggerDate(section string) string {
+	m := changelogHeadingReg.FindStringSubmatchIndex(section)
+	if m == nil || m[6] < 0 {
+		return section
+	}
+	tag := section[m[2]:m[3]]
+	date, _, err := tp.c.Git("for-each-ref", "--format=%(taggerdate:short)", "refs/tags/"+tag)
+	if err != nil || date == "" {
+		return section
+	}
+	return section[:m[6]] + date + section[m[7]:]
+}
+
+// isEmptyNotes reports whether the changelog section has nothing but the heading
+func isEmptyNotes(changelog string) bool {
+	stuffs := strings.SplitN(strings.TrimSpace(changelog), "\n", 2)
+	return len(stuffs) < 2 || strings.TrimSpace(stuffs[1]) == ""
+}
+
+// insertBeforeFullChangelog inserts the section before the "**Full Changelog**" line
+// of the release notes generated by GitHub.
+func insertBeforeFullChangelog(notes, section string) string {
+	const fullChangelog = "**Full Changelog**"
+	if i := strings.LastIndex(notes, fullChangelog); i >= 0 {
+		return notes[:i] + section + "\n" + notes[i:]
+	}
+	return strings.TrimSpace(notes) + "\n\n" + section
+}
+
+// runCommand runs tagpr.command and returns its combined output of stdout and stderr.
+// Errors of the command are ignored except for the timeout.
+func (tp *tagpr) runCommand(ctx context.Context, com string) (string, error) {
+	prog := com
+	var progArgs []string
+	if strings.ContainsAny(prog, " \n") {
+		prog = "sh"
+		progArgs = []string{"-c", com}
+	}
+	timeout, err := tp.cfg.CommandTimeout()
+	if err != nil {
+		return "", err
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	stdout, stderr, _ := tp.c.CmdContext(ctx, prog, progArgs...)
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return "", fmt.Errorf("%s timed out after %s and was killed: %s", configCommand, timeout, com)
+	}
+	return strings.TrimSpace(stdout + "\n" + stderr), nil
+}
+
+// versionFromCommand runs tagpr.versionCommand and returns its stdout as the next version
+func (tp *tagpr) versionFromCommand(ctx context.Context, com string) (*semv, error) {
+	timeout, err := tp.cfg.CommandTimeout()
+	if err != nil {
+		return nil, err
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	out, _, err := tp.c.CmdContext(ctx, "sh", "-c", com)
+	if err != nil {
+		return nil, fmt.Errorf("%s failed: %w", configVersionCommand, err)
+	}
+	v, err := newSemver(out)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s returned the invalid version %q: %s",
+			ErrInvalidConfig, configVersionCommand, out, err)
+	}
+	return v, nil
+}
+
+// preflight runs tagpr.preflight. The non-zero exit of the command is the veto, which is
+// distinguished from the failure to run it.
+func (tp *tagpr) preflight(ctx context.Context, com string) error {
+	timeout, err := tp.cfg.CommandTimeout()
+	if err != nil {
+		return err
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	_, stderr, err := tp.c.CmdContext(ctx, "sh", "-c", com)
+	var exitErr *exec.ExitError
+	if err != nil && ctx.Err() == nil && errors.As(err, &exitErr) {
+		log.Printf("the release is vetoed by %s (exit status %d): %s\n", configPreflight, exitErr.ExitCode(), stderr)
+		return fmt.Errorf("%w by %s: %s", ErrVetoed, configPreflight, com)
+	}
+	if err != nil {
+		return fmt.Errorf("%s failed: %w", configPreflight, err)
+	}
+	return nil
+}
+
+// transformBody passes the body to stdin of tagpr.bodyCommand and returns its stdout
+// as the new body. Unlike tagpr.command, the failure of the command is an error.
+func (tp *tagpr) transformBody(ctx context.Context, com, body string) (string, error) {
+	timeout, err := tp.cfg.CommandTimeout()
+	if err != nil {
+		return "", err
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	out, _, err := tp.c.CmdWithInput(ctx, strings.NewReader(body), "sh", "-c", com)
+	if err != nil {
+		return "", fmt.Errorf("%s failed: %w", configBodyCommand, err)
+	}
+	if out == "" {
+		return "", fmt.Errorf("%s returned the empty body: %s", configBodyCommand, com)
+	}
+	return out, nil
+}
+
+// gitPush runs "git push". If the push token is specified by the environment variable,
+// the pushes are authenticated with it through the credential helper, instead of the
+// credentials persisted by actions/checkout, to bypass branch protections for example.
+// The token itself is not passed as an argument to prevent it from being logged.
+func (tp *tagpr) gitPush(args ...string) (string, string, error) {
+	if os.Getenv(envPushToken) == "" {
+		return tp.c.Git(append([]string{"push"}, args...)...)
+	}
+	gitArgs := []string{
+		"-c", fmt.Sprintf("http.https://%s/.extraheader=", tp.host),
+		"-c", "credential.helper=",
+		"-c", `credential.helper=!f() { echo username=x-access-token; echo "password=$` + envPushToken + `"; }; f`,
+		"push",
+	}
+	return tp.c.Git(append(gitArgs, args...)...)
+}
+
+// forced reports whether the failed safety check is bypassed by the --force flag,
+// and logs the overridden check loudly.
+func (tp *tagpr) forced(err error) bool {
+	if !tp.force {
+		return false
+	}
+	log.Printf("WARNING: the safety check is overridden by --force: %s\n", err)
+	return true
+}
+
+// checkWorktree returns an error listing the changed files if the working tree has
+// uncommitted changes of tracked files, since the tagpr modifies and commits files.
+func (tp *tagpr) checkWorktree() error {
+	out, _, err := tp.c.Git("status", "--porcelain", "--untracked-files=no")
+	if err != nil {
+		return err
+	}
+	if out != "" {
+		return fmt.Errorf("%w: the working tree has uncommitted changes. Commit or stash them, "+
+			"or set %s=true to proceed:\n%s", ErrDirtyWorktree, configAllowDirtyWorktree, out)
+	}
+	return nil
+}
+
+// isTaggedHEAD reports whether the tag points at the HEAD commit
+func (tp *tagpr) isTaggedHEAD(tag string) (bool, error) {
+	// the tag doesn't exist, e.g. before tagging
+	if out, _, err := tp.c.Git("tag", "--list", tag); err != nil || out == "" {
+		return false, err
+	}
+	tagCommit, _, err := tp.c.Git("rev-parse", tag+"^{commit}")
+	if err != nil {
+		return false, err
+	}
+	head, _, err := tp.c.Git("rev-parse", "HEAD")
+	if err != nil {
+		return false, err
+	}
+	return tagCommit == head, nil
+}
+
+// releaseMerge tags the merge commit of the release pull request and creates the release, unless
+// the pull request is treated as a non-release one by the labels. It reports whether the merge is
+// released, or vetoed in the freeze window with the error.
+func (tp *tagpr) releaseMerge(ctx context.Context, pr *github.PullRequest, sha string, currVer *semv,
+	latestSemverTag, branch string) (bool, error) {
+	// The merge of the pull request with no release labels is treated as a non-release one.
+	if l := matchedLabel(pr.Labels, tp.cfg.NoReleaseLabels()); l != "" {
+		log.Printf("the merged pull request #%d has the label %q, so skip tagging\n", pr.GetNumber(), l)
+		return false, nil
+	}
+	if req := tp.cfg.PRLabelsRequiredToTag(); len(req) > 0 && matchedLabel(pr.Labels, req) == "" {
+		// The merge without the required labels is also treated as a non-release one.
+		log.Printf("the merged pull request #%d has none of the labels %v required by %s, so skip tagging\n",
+			pr.GetNumber(), req, configPRLabelsRequiredToTag)
+		return false, nil
+	}
+	// The version to be tagged is computed regardless of the gates below for --version-out.
+	if tp.versionOnly {
+		return true, tp.tagRelease(ctx, pr, sha, currVer, latestSemverTag, branch)
+	}
+	// The freeze window is checked against the time of the run rather than the merge,
+	// so that the merge in the window is tagged by the run after the window.
+	window, err := tp.cfg.frozen(time.Now())
+	if err != nil {
+		return false, err
+	}
+	if window != "" {
+		err := fmt.Errorf("%w: in the freeze window of %s", ErrVetoed, window)
+		if !tp.forced(err) {
+			return false, tp.noopErr(reasonFrozen, err)
+		}
+	}
+	if tp.cfg.RequireChecklist() {
+		if err := checkChecklist(pr); err != nil && !tp.forced(err) {
+			return false, err
+		}
+	}
+	if tp.cfg.WaitForChecks() {
+		timeout, err := tp.cfg.ChecksTimeout()
+		if err != nil {
+			return false, err
+		}
+		if err := tp.waitForChecks(ctx, branch, sha, timeout); err != nil {
+			return false, err
+		}
+	}
+	return true, tp.tagRelease(ctx, pr, sha, currVer, latestSemverTag, branch)
+}
+
+// untaggedTagPR returns the latest release pull request merged into the branch, if its merge commit
+// is in HEAD and not tagged yet, that is, not in the latest tag.
+func (tp *tagpr) untaggedTagPR(ctx context.Context, branch string, tf tagFormat, latestSemverTag string) (*github.PullRequest, error) {
+	pulls, _, err := tp.gh.PullRequests.List(ctx, tp.owner, tp.repo, &github.PullRequestListOptions{
+		State:       "closed",
+		Base:        branch,
+		Sort:        "updated",
+		Direction:   "desc",
+		ListOptions: github.ListOptions{PerPage: 100},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var latest *github.PullRequest
+	for _, pr := range pulls {
+		if pr.MergedAt == nil || pr.GetMergeCommitSHA() == "" ||
+			matchedLabel(pr.Labels, []string{autoLableName}) == "" || !isTagPR(pr, tf) {
+			continue
+		}
+		if latest == nil || pr.GetMergedAt().After(latest.GetMergedAt()) {
+			latest = pr
+		}
+	}
+	if latest == nil {
+		return nil, nil
+	}
+	sha := latest.GetMergeCommitSHA()
+	// the merge commit may be missing locally, e.g. by the force push
+	if _, _, err := tp.c.Git("merge-base", "--is-ancestor", sha, "HEAD"); err != nil {
+		return nil, nil
+	}
+	if latestSemverTag != "" {
+		if _, _, err := tp.c.Git("merge-base", "--is-ancestor", sha, latestSemverTag); err == nil {
+			return nil, nil
+		}
+	}
+	return latest, nil
+}
+
+// onlyReleaseFilesChanged reports whether the changes since the tag are only in the
+// version files, the changelog and the configuration file.
+func (tp *tagpr) onlyReleaseFilesChanged(tag string, currVer *semv) (bool, error) {
+	nf, err := tp.cfg.NotesFormat()
+	if err != nil {
+		return false, err
+	}
+	files := map[string]bool{nf.file(): true, defaultConfigFile: true}
+	if vf := tp.cfg.VersionFile(); vf != nil {
+		for _, f := range vf.List() {
+			fpath, _ := splitVersionFile(f)
+			files[fpath] = true
+		}
+	} else if f, err := detectVersionFile(".", currVer); err == nil && f != "" {
+		files[f] = true
+	}
+	out, _, err := tp.c.Git("diff", "--name-only", tag, "HEAD")
+	if err != nil {
+		return false, err
+	}
+	for _, f := range strings.Split(out, "\n") {
+		if f != "" && !files[f] {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// versionFiles returns the configured version files. Missing files are skipped
+// with a warning or cause an error according to tagpr.versionFileMissing.
+func (tp *tagpr) versionFiles() ([]string, error) {
+	vf := tp.cfg.VersionFile()
+	if vf == nil || vf.Empty() {
+		return nil, nil
+	}
+	missing := tp.cfg.VersionFileMissing()
+	if missing != versionFileMissingSkip && missing != versionFileMissingError {
+		return nil, fmt.Errorf("%w: %s: %q", ErrInvalidConfig, configVersionFileMissing, missing)
+	}
+	var vfiles []string
+	for _, f := range strings.Split(vf.String(), ",") {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		if _, err := versionFileVPrefix(f); err != nil {
+			return nil, err
+		}
+		if fpath, _ := splitVersionFile(f); !exists(fpath) {
+			if missing == versionFileMissingSkip {
+				log.Printf("version file %q is not found, so skip it\n", f)
+				continue
+			}
+			return nil, fmt.Errorf("%w: version file %q is not found", ErrInvalidVersionFile, f)
+		}
+		vfiles = append(vfiles, f)
+	}
+	return vfiles, nil
+}
+
+// guessNext guesses the next version. The version specified by the "next-version: X.Y.Z" line
+// in the body of the release pull request takes precedence. Otherwise it is guessed from
+// the labels of the release pull request.
+// If no labels for bumping are added, it detects the bump level from the titles of
+// the release pull request and the pull requests merged since the latest tag when
+// tagpr.titleBumpPattern is configured.
+func (tp *tagpr) guessNext(ctx context.Context, currVer *semv, pr *github.PullRequest, latestTag string) (*semv, error) {
+	if com := tp.cfg.VersionCommand(); com != "" {
+		nextVer, err := tp.versionFromCommand(ctx, com)
+		if err != nil {
+			return nil, err
+		}
+		nextVer.vPrefix, nextVer.format = currVer.vPrefix, currVer.format
+		return nextVer, nil
+	}
+	if v := nextVersionFromBody(pr.GetBody()); v != "" {
+		nextVer, err := newSemver(v)
+		if err == nil {
+			nextVer.vPrefix, nextVer.format = currVer.vPrefix, currVer.format
+			return nextVer, nil
+		}
+		log.Printf("invalid version %q is specified in the pull request body, so ignore it: %s\n", v, err)
+	}
+
+	var labels []*github.Label
+	if pr != nil {
+		labels = pr.Labels
+	}
+	var sources []bumpSource
+	if tp.cfg.MajorOnBreaking() {
+		breaking, err := tp.hasBreakingPulls(ctx, latestTag)
+		if err != nil {
+			return nil, err
+		}
+		var b string
+		if breaking {
+			b = bumpMajor
+		}
+		sources = append(sources, bumpSource{name: "breaking changes", bump: b})
+	}
+	sources = append(sources, bumpSource{name: "labels", bump: bumpFromLabels(labels)})
+	if pat := tp.cfg.TitleBumpPattern(); pat != nil && !pat.Empty() {
+		reg, err := regexp.Compile(pat.String())
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %s", ErrInvalidConfig, configTitleBumpPattern, err)
+		}
+		titles, err := tp.mergedTitles(latestTag)
+		if err != nil {
+			return nil, err
+		}
+		if pr != nil {
+			titles = append([]string{pr.GetTitle()}, titles...)
+		}
+		sources = append(sources, bumpSource{name: "titles", bump: bumpFromTitles(reg, titles)})
+	}
+	bump, source := resolveBump(sources...)
+	log.Printf("the bump level %q is adopted from the %s\n", bump, source)
+	return currVer.Next(bump), nil
+}
+
+var nextVersionReg = regexp.MustCompile(`(?m)^[ \t]*next-version:[ \t]*(\S+)[ \t]*\r?$`)
+
+// keepNextVersion keeps the version overridden by maintainers in the old body for the following
+// runs, even if it was written in the region rewritten by the tagpr.
+func keepNextVersion(old, body string) string {
+	if m := nextVersionReg.FindString(old); m != "" && !nextVersionReg.MatchString(body) {
+		body += "\n\n" + strings.TrimSpace(m)
+	}
+	return body
+}
+
+// nextVersionFromBody retrieves the version overridden by maintainers in the pull request body
+func nextVersionFromBody(body string) string {
+	if m := nextVersionReg.FindStringSubmatch(body); len(m) > 1 {
+		return m[1]
+	}
+	return ""
+}
+
+// hasBreakingPulls reports whether any of the pull requests merged since the specified tag
+// have tagpr.breakingLabels.
+func (tp *tagpr) hasBreakingPulls(ctx context.Context, from string) (bool, error) {
+	if len(tp.cfg.BreakingLabels()) == 0 {
+		return false, nil
+	}
+	nums, err := tp.mergedPullNumbers(from, "HEAD")
+	if err != nil {
+		return false, err
+	}
+	labelsOf := tp.pullLabels(ctx)
+	for _, num := range nums {
+		labels, err := labelsOf(num)
+		if err != nil {
+			return false, err
+		}
+		if hasAnyLabel(labels, tp.cfg.BreakingLabels()) {
+			log.Printf("the merged pull request #%d is a breaking change\n", num)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// mergedTitles retrieves titles of pull requests merged since the specified tag from
+// the first-parent commit history. The title is the commit subject for "Squash and merge"
+// and the first line of the commit body for "Create a merge commit".
+func (tp *tagpr) mergedTitles(from string) ([]string, error) {
+	args := []string{"log", "--first-parent", "--format=%s%x00%b%x1e"}
+	if from != "" {
+		args = append(args, from+"..HEAD")
+	}
+	out, _, err := tp.c.Git(args...)
+	if err != nil {
+		return nil, err
+	}
+	var titles []string
+	for _, commit := range strings.Split(out, "\x1e") {
+		stuffs := strings.SplitN(strings.TrimSpace(commit), "\x00", 2)
+		title := stuffs[0]
+		if strings.HasPrefix(title, "Merge pull request #") && len(stuffs) > 1 {
+			title = strings.SplitN(strings.TrimSpace(stuffs[1]), "\n", 2)[0]
+		}
+		if title != "" {
+			titles = append(titles, title)
+		}
+	}
+	return titles, nil
 }
 
 var (
@@ -382,6 +1698,19 @@ var (
 	scpLikeURLReg = regexp.MustCompile("^([^@]+@)?([^:]+):(/?.+)$")
 )
 
+// parseRemote returns the host, the owner and the repo name from the remote URL
+func parseRemote(remoteURL string) (host, owner, repo string, err error) {
+	u, err := parseGitURL(remoteURL)
+	if err != nil {
+		return "", "", "", fmt.Errorf("failed to parse remote URL: %s", remoteURL)
+	}
+	m := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(m) < 2 || u.Hostname() == "" {
+		return "", "", "", fmt.Errorf("failed to detect owner and repo from remote URL: %s", remoteURL)
+	}
+	return u.Hostname(), m[0], strings.TrimSuffix(m[1], ".git"), nil
+}
+
 func parseGitURL(u string) (*url.URL, error) {
 	if !hasSchemeReg.MatchString(u) {
 		if m := scpLikeURLReg.FindStringSubmatch(u); len(m) == 4 {
@@ -391,9 +1720,23 @@ func parseGitURL(u string) (*url.URL, error) {
 	return url.Parse(u)
 }
 
+const (
+	bodyStartMarker = "<!-- tagpr:start -->"
+	bodyEndMarker   = "<!-- tagpr:end -->"
+)
+
+// mergeBody rewrites only the region between the markers in the current body with the
+// update, to preserve human additions outside the region. If the current body has no
+// markers, the whole body is replaced.
 func mergeBody(now, update string) string {
 	// TODO: If there are check boxes, respect what is checked, etc.
-	return update
+	wrapped := bodyStartMarker + "\n" + update + "\n" + bodyEndMarker
+	start := strings.Index(now, bodyStartMarker)
+	end := strings.Index(now, bodyEndMarker)
+	if start < 0 || end < start {
+		return wrapped
+	}
+	return now[:start] + wrapped + now[end+len(bodyEndMarker):]
 }
 
 var headBranchReg = regexp.MustCompile(`(?m)^\s*HEAD branch: (.*)$`)
@@ -403,7 +1746,7 @@ func (tp *tagpr) defaultBranch() (string, error) {
 	// So use `git remote show origin` for detecting default branch
 	show, _, err := tp.c.Git("remote", "show", tp.remoteName)
 	if err != nil {
-		return "", fmt.Errorf("failed to detect defaut branch: %w", err)
+		return "", fmt.Errorf("failed to detect default branch: %w", err)
 	}
 	m := headBranchReg.FindStringSubmatch(show)
 	if len(m) < 2 {
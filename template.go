@@ -2,7 +2,13 @@ package tagpr
 
 import (
 	"bytes"
+	"html"
+	htmltemplate "html/template"
+	"io"
 	"log"
+	"path/filepath"
+	"regexp"
+	"strings"
 	"text/template"
 )
 
@@ -15,7 +21,7 @@ You can modify this branch "{{.Branch}}" directly before merging if you want to
 <details>
 <summary>How to change the next version as you like</summary>
 
-There are two ways to do it.
+There are three ways to do it.
 
 - Version file
     - Edit and commit the version file specified in the .tagpr configuration file to describe the next version
@@ -23,34 +29,132 @@ There are two ways to do it.
 - Labels convention
     - Add labels to this pull request like "tagpr:minor" or "tagpr:major"
     - If no conventional labels are added, the patch version is incremented as is.
+- Pull request body
+    - Add a line like "next-version: 2.0.0" to this pull request body
 </details>
 
 ---
 {{.Changelog}}`
 
-var defaultTmpl *template.Template
+const (
+	tmplEngineText = "text"
+	tmplEngineHTML = "html"
+)
+
+// tmplFuncs are the functions available in the pull request template
+var (
+	tmplFuncs = template.FuncMap{
+		"htmlEscape": template.HTMLEscapeString,
+	}
+	// htmlEscape is the no-op for the "html" engine not to escape twice
+	htmlTmplFuncs = htmltemplate.FuncMap{
+		"htmlEscape": func(s string) htmltemplate.HTML {
+			return htmltemplate.HTML(htmltemplate.HTMLEscapeString(s))
+		},
+	}
+)
+
+var (
+	defaultTmpl     *template.Template
+	defaultHTMLTmpl *htmltemplate.Template
+)
 
 func init() {
 	var err error
-	defaultTmpl, err = template.New("pull request template").Parse(defaultTmplStr)
+	defaultTmpl, err = template.New("pull request template").Funcs(tmplFuncs).Parse(defaultTmplStr)
 	if err != nil {
 		log.Fatal(err)
 	}
+	defaultHTMLTmpl, err = htmltemplate.New("pull request template").
+		Funcs(htmlTmplFuncs).Parse(defaultTmplStr)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+type executor interface {
+	Execute(io.Writer, interface{}) error
+}
+
+// parsePRTmpl parses the pull request template file by the engine of tagpr.prTemplateEngine
+func parsePRTmpl(fpath, engine string) (executor, error) {
+	name := filepath.Base(fpath)
+	if engine == tmplEngineHTML {
+		tmpl, err := htmltemplate.New(name).Funcs(htmlTmplFuncs).ParseFiles(fpath)
+		if err != nil {
+			return nil, err
+		}
+		return tmpl, nil
+	}
+	tmpl, err := template.New(name).Funcs(tmplFuncs).ParseFiles(fpath)
+	if err != nil {
+		return nil, err
+	}
+	return tmpl, nil
 }
 
 type tmplArg struct {
 	NextVersion, Branch, Changelog string
+	// Scopes is the titles of the merged pull requests in the conventional commits
+	// format grouped by the scope. The titles without the scope are keyed by "".
+	Scopes map[string][]string
+	// BreakingChanges is the lines of the pull requests with tagpr.breakingLabels in the release notes
+	BreakingChanges []string
+	// Highlights is the lines of the pull requests with tagpr.highlightReactions or more :+1: reactions
+	Highlights []string
+	// Reactions is the numbers of the :+1: reactions keyed by the pull request number.
+	// It is available only if tagpr.highlightReactions is set.
+	Reactions map[int]int
+	// Checklist is the task items in the current body of the release pull request
+	Checklist []checkItem
+}
+
+type checkItem struct {
+	Text    string
+	Checked bool
+}
+
+var taskItemReg = regexp.MustCompile(`(?m)^[ \t]*[-*] \[([ xX])\] (.*?)[ \t]*$`)
+
+// parseChecklist parses the task items of markdown like "- [x] Check the docs"
+func parseChecklist(body string) []checkItem {
+	var items []checkItem
+	for _, m := range taskItemReg.FindAllStringSubmatch(body, -1) {
+		items = append(items, checkItem{Text: m[2], Checked: m[1] != " "})
+	}
+	return items
 }
 
-func newPRTmpl(tmpl *template.Template) *prTmpl {
-	if tmpl == nil {
-		tmpl = defaultTmpl
+var conventionalTitleReg = regexp.MustCompile(`^[a-zA-Z]+(?:\(([^)]*)\))?!?: `)
+
+// groupByScope groups the titles in the conventional commits format (e.g. "feat(api): add x")
+// by the scope. The titles not in the format are ignored.
+func groupByScope(titles []string) map[string][]string {
+	scopes := map[string][]string{}
+	for _, title := range titles {
+		m := conventionalTitleReg.FindStringSubmatch(title)
+		if m == nil {
+			continue
+		}
+		scopes[m[1]] = append(scopes[m[1]], title)
 	}
-	return &prTmpl{tmpl: tmpl}
+	return scopes
+}
+
+func newPRTmpl(tmpl executor, engine string) *prTmpl {
+	pt := &prTmpl{tmpl: tmpl, fallback: defaultTmpl}
+	if engine == tmplEngineHTML {
+		pt.fallback, pt.html = defaultHTMLTmpl, true
+	}
+	if pt.tmpl == nil {
+		pt.tmpl = pt.fallback
+	}
+	return pt
 }
 
 type prTmpl struct {
-	tmpl *template.Template
+	tmpl, fallback executor
+	html           bool
 }
 
 func (pt *prTmpl) Render(arg *tmplArg) (string, error) {
@@ -60,7 +164,14 @@ func (pt *prTmpl) Render(arg *tmplArg) (string, error) {
 		log.Printf("failed to render configured template: %s\n", err)
 		b.Reset()
 		// fallback to default template
-		err = defaultTmpl.Execute(&b, arg)
+		err = pt.fallback.Execute(&b, arg)
+	}
+	text := b.String()
+	if pt.html {
+		// The first line is the title of the pull request, which is the plain text.
+		stuffs := strings.SplitN(text, "\n", 2)
+		stuffs[0] = html.UnescapeString(stuffs[0])
+		text = strings.Join(stuffs, "\n")
 	}
-	return b.String(), err
+	return text, err
 }
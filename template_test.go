@@ -0,0 +1,49 @@
+package tagpr
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPRTmpl_specialCharacters(t *testing.T) {
+	fpath := filepath.Join(t.TempDir(), "tagpr.tmpl")
+	const tmplStr = "Release for {{.NextVersion}} <{{.Branch}}>\n\n{{.Changelog}}\n{{htmlEscape .Changelog}}\n"
+	if err := os.WriteFile(fpath, []byte(tmplStr), 0666); err != nil {
+		t.Fatal(err)
+	}
+	arg := &tmplArg{
+		NextVersion: "v1.2.3",
+		Branch:      "tagpr-from-v1.2.2",
+		Changelog:   "* Support <T> & generics by @Songmu in https://github.com/Songmu/tagpr/pull/1",
+	}
+	testCases := []struct {
+		engine, expect string
+	}{{
+		engine: tmplEngineText,
+		expect: "Release for v1.2.3 <tagpr-from-v1.2.2>\n\n" +
+			"* Support <T> & generics by @Songmu in https://github.com/Songmu/tagpr/pull/1\n" +
+			"* Support &lt;T&gt; &amp; generics by @Songmu in https://github.com/Songmu/tagpr/pull/1\n",
+	}, {
+		engine: tmplEngineHTML,
+		// The title is unescaped as the plain text, and htmlEscape is not applied twice.
+		expect: "Release for v1.2.3 <tagpr-from-v1.2.2>\n\n" +
+			"* Support &lt;T&gt; &amp; generics by @Songmu in https://github.com/Songmu/tagpr/pull/1\n" +
+			"* Support &lt;T&gt; &amp; generics by @Songmu in https://github.com/Songmu/tagpr/pull/1\n",
+	}}
+	for _, tc := range testCases {
+		t.Run(tc.engine, func(t *testing.T) {
+			tmpl, err := parsePRTmpl(fpath, tc.engine)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := newPRTmpl(tmpl, tc.engine).Render(arg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.expect {
+				t.Errorf("got:\n%s\nexpected:\n%s", got, tc.expect)
+			}
+		})
+	}
+}
@@ -0,0 +1,10 @@
+plugins {
+    `java-library`
+}
+
+dependencies {
+    implementation("com.example:sibling:1.4.2")
+}
+
+group = "com.example"
+version = "1.4.2"
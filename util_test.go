@@ -0,0 +1,567 @@
+package tagpr
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v47/github"
+)
+
+const (
+	testOwner = "Songmu"
+	testRepo  = "tagpr-test"
+)
+
+// testGitRepo is the working clone of the bare remote repository for the tests running the tagpr.
+// The remote URL is the one of GitHub rewritten to the bare repository by url.<base>.insteadOf,
+// so that both the tagpr and gh2changelog detect the owner and the repo from it.
+type testGitRepo struct {
+	t           *testing.T
+	dir, remote string
+}
+
+// newTestRepo creates the repository with the initial commit of the .tagpr file and version.txt
+// on the main branch, and moves into it until the end of the test.
+func newTestRepo(t *testing.T, tagprConf string) *testGitRepo {
+	t.Helper()
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	globalConf := filepath.Join(root, "gitconfig")
+	if err := os.WriteFile(globalConf, []byte(
+		"[user]\n\tname = tagpr-test\n\temail = tagpr-test@example.com\n[init]\n\tdefaultBranch = main\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GIT_CONFIG_GLOBAL", globalConf)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GITHUB_TOKEN", "dummy")
+	t.Setenv("GITHUB_OUTPUT", "")
+	// the remote URL is rewritten to the local path, so the repository is given explicitly
+	t.Setenv(envOwner, testOwner)
+	t.Setenv(envRepo, testRepo)
+	t.Setenv(envHost, "github.com")
+
+	r := &testGitRepo{t: t, dir: filepath.Join(root, "work"), remote: filepath.Join(root, "remote.git")}
+	r.gitIn(root, "init", "--bare", "-b", "main", r.remote)
+	r.gitIn(root, "init", "-b", "main", r.dir)
+	ghURL := fmt.Sprintf("https://github.com/%s/%s.git", testOwner, testRepo)
+	r.git("remote", "add", "origin", ghURL)
+	r.git("config", "url."+r.remote+".insteadOf", ghURL)
+
+	if tagprConf == "" {
+		tagprConf = "[tagpr]\n\treleaseBranch = main\n\tversionFile = version.txt\n\tvPrefix = true\n"
+	}
+	r.write(".tagpr", tagprConf)
+	r.write("version.txt", "version: 0.0.0\n")
+	r.commit("initial commit")
+	r.git("push", "-u", "origin", "main")
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(r.dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	return r
+}
+
+func (r *testGitRepo) gitIn(dir string, args ...string) string {
+	r.t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		r.t.Fatalf("git %s: %s\n%s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func (r *testGitRepo) git(args ...string) string {
+	r.t.Helper()
+	return r.gitIn(r.dir, args...)
+}
+
+// remoteGit runs git in the bare remote repository
+func (r *testGitRepo) remoteGit(args ...string) string {
+	r.t.Helper()
+	return r.gitIn(r.remote, args...)
+}
+
+func (r *testGitRepo) write(fpath, content string) {
+	r.t.Helper()
+	fpath = filepath.Join(r.dir, fpath)
+	if err := os.MkdirAll(filepath.Dir(fpath), 0755); err != nil {
+		r.t.Fatal(err)
+	}
+	if err := os.WriteFile(fpath, []byte(content), 0666); err != nil {
+		r.t.Fatal(err)
+	}
+}
+
+func (r *testGitRepo) commit(msg string) string {
+	r.t.Helper()
+	r.git("add", "-A")
+	r.git("commit", "--allow-empty", "-m", msg)
+	return r.git("rev-parse", "HEAD")
+}
+
+// runTagPR runs the tagpr on the release branch freshly pulled from the remote, as on the
+// checkout of GitHub Actions, with the API client for the fake.
+func (r *testGitRepo) runTagPR(fake *fakeGitHub, branch string) (*tagpr, error) {
+	r.t.Helper()
+	tp := r.newTagPR(fake, branch)
+	return tp, tp.Run(context.Background())
+}
+
+// newTagPR checks out the branch as the remote and returns the tagpr using the fake
+func (r *testGitRepo) newTagPR(fake *fakeGitHub, branch string) *tagpr {
+	r.t.Helper()
+	r.git("checkout", "-f", branch)
+	r.git("fetch", "origin")
+	r.git("reset", "--hard", "origin/"+branch)
+	tp, err := newTagPR(context.Background(), &commander{
+		gitPath: "git", outStream: io.Discard, errStream: io.Discard, dir: "."}, "")
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	tp.gh = fake.client()
+	return tp
+}
+
+// mergePull merges the head branch of the pull request into the base branch on the remote
+// by "Create a merge commit", and marks the pull request merged as GitHub does.
+func (r *testGitRepo) mergePull(fake *fakeGitHub, num int) {
+	r.t.Helper()
+	fake.mu.Lock()
+	pr := fake.pulls[num-1]
+	fake.mu.Unlock()
+	base, head := pr.GetBase().GetRef(), pr.GetHead().GetRef()
+	r.git("fetch", "origin")
+	r.git("checkout", "-f", base)
+	r.git("reset", "--hard", "origin/"+base)
+	r.git("merge", "--no-ff", "origin/"+head, "-m",
+		fmt.Sprintf("Merge pull request #%d from %s/%s\n\n%s", num, testOwner, head, pr.GetTitle()))
+	r.git("push", "origin", base)
+	sha := r.git("rev-parse", "HEAD")
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	now := time.Now()
+	pr.State, pr.Merged, pr.MergedAt, pr.MergeCommitSHA = github.String("closed"), github.Bool(true), &now, &sha
+}
+
+// pushChange pushes the commit adding the file onto the main branch of the remote
+func (r *testGitRepo) pushChange(fpath string) {
+	r.t.Helper()
+	r.git("checkout", "-f", "main")
+	r.git("pull", "origin", "main")
+	r.write(fpath, fpath)
+	r.commit("add " + fpath)
+	r.git("push", "origin", "main")
+}
+
+// release goes through the release cycle for the new commit on the main branch, that is,
+// creating the release pull request, merging it and tagging the merge.
+func (r *testGitRepo) release(fake *fakeGitHub, fpath string) *tagpr {
+	r.t.Helper()
+	r.pushChange(fpath)
+	if _, err := r.runTagPR(fake, "main"); err != nil {
+		r.t.Fatal(err)
+	}
+	pulls := fake.openPulls()
+	if len(pulls) == 0 {
+		r.t.Fatal("no release pull request is open")
+	}
+	r.mergePull(fake, pulls[0].GetNumber())
+	tp, err := r.runTagPR(fake, "main")
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	return tp
+}
+
+// fakeGitHub is the in-memory fake of the GitHub REST API for the repository used by the tagpr.
+// The head SHAs of the pull requests are resolved from the bare remote repository.
+type fakeGitHub struct {
+	t      *testing.T
+	remote string
+	srv    *httptest.Server
+
+	mu        sync.Mutex
+	pulls     []*github.PullRequest
+	comments  map[int][]*github.IssueComment
+	reactions map[int]int
+	releases  []*github.RepositoryRelease
+	assets    map[int64][]*github.ReleaseAsset
+	// requiredChecks are the required status checks of the branch protection. nil means
+	// the branch is not protected.
+	requiredChecks []string
+	checkRuns      []*github.CheckRun
+	statuses       []*github.RepoStatus
+	nextID         int64
+	// notes renders the body of the generated release notes
+	notes func(tag, prev string) string
+	// failUpload fails the uploads of the release assets
+	failUpload bool
+}
+
+// newFakeGitHub starts the fake GitHub API for the repository. The repository can be nil
+// for the tests calling the API directly without git.
+func newFakeGitHub(t *testing.T, r *testGitRepo) *fakeGitHub {
+	f := &fakeGitHub{
+		t:         t,
+		comments:  map[int][]*github.IssueComment{},
+		reactions: map[int]int{},
+		assets:    map[int64][]*github.ReleaseAsset{},
+	}
+	if r != nil {
+		f.remote = r.remote
+	}
+	f.srv = httptest.NewServer(f)
+	t.Cleanup(f.srv.Close)
+	return f
+}
+
+func (f *fakeGitHub) client() *github.Client {
+	cli := github.NewClient(nil)
+	u, _ := url.Parse(f.srv.URL + "/")
+	cli.BaseURL, cli.UploadURL = u, u
+	return cli
+}
+
+// route matches the path of the request to the pattern, whose "*" segments are captured.
+// The last "**" segment captures the rest of the path.
+func route(path, pattern string) ([]string, bool) {
+	segs := strings.Split(strings.Trim(path, "/"), "/")
+	pats := strings.Split(pattern, "/")
+	var captured []string
+	for i, p := range pats {
+		if p == "**" && i < len(segs) {
+			return append(captured, strings.Join(segs[i:], "/")), true
+		}
+		if i >= len(segs) {
+			return nil, false
+		}
+		switch p {
+		case "*":
+			captured = append(captured, segs[i])
+		case segs[i]:
+		default:
+			return nil, false
+		}
+	}
+	return captured, len(segs) == len(pats)
+}
+
+func (f *fakeGitHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	prefix := fmt.Sprintf("/repos/%s/%s/", testOwner, testRepo)
+	if !strings.HasPrefix(r.URL.Path, prefix) {
+		f.t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		http.NotFound(w, r)
+		return
+	}
+	path := strings.TrimPrefix(r.URL.Path, prefix)
+	num := func(s string) int {
+		n, _ := strconv.Atoi(s)
+		return n
+	}
+	m := func(method, pattern string) []string {
+		if r.Method != method {
+			return nil
+		}
+		if c, ok := route(path, pattern); ok {
+			return append(c, "")
+		}
+		return nil
+	}
+	if c := m("GET", "commits/*/pulls"); c != nil {
+		var pulls []*github.PullRequest
+		for _, pr := range f.pulls {
+			if pr.GetMergeCommitSHA() == c[0] {
+				pulls = append(pulls, f.fill(pr))
+			}
+		}
+		f.json(w, pulls)
+	} else if c := m("GET", "pulls"); c != nil {
+		q := r.URL.Query()
+		state := q.Get("state")
+		if state == "" {
+			state = "open"
+		}
+		var pulls []*github.PullRequest
+		for i := len(f.pulls) - 1; i >= 0; i-- {
+			pr := f.pulls[i]
+			if state != "all" && pr.GetState() != state ||
+				q.Get("base") != "" && pr.GetBase().GetRef() != q.Get("base") ||
+				q.Get("head") != "" && testOwner+":"+pr.GetHead().GetRef() != q.Get("head") {
+				continue
+			}
+			pulls = append(pulls, f.fill(pr))
+		}
+		f.json(w, pulls)
+	} else if c := m("POST", "pulls"); c != nil {
+		var npr github.NewPullRequest
+		f.decode(r, &npr)
+		pr := &github.PullRequest{
+			Number: github.Int(len(f.pulls) + 1),
+			State:  github.String("open"),
+			Title:  npr.Title,
+			Body:   npr.Body,
+			Base:   &github.PullRequestBranch{Ref: npr.Base},
+			Head: &github.PullRequestBranch{
+				Ref:  github.String(strings.TrimPrefix(npr.GetHead(), testOwner+":")),
+				Repo: &github.Repository{FullName: github.String(testOwner + "/" + testRepo)},
+			},
+			NodeID: github.String("PR_node"),
+		}
+		f.pulls = append(f.pulls, pr)
+		f.json(w, f.fill(pr))
+	} else if c := m("GET", "pulls/*"); c != nil {
+		f.json(w, f.fill(f.pull(w, num(c[0]))))
+	} else if c := m("PATCH", "pulls/*"); c != nil {
+		pr := f.pull(w, num(c[0]))
+		// the base is sent as the branch name, not as the object
+		var edit struct {
+			Title, Body, State *string
+		}
+		f.decode(r, &edit)
+		if edit.Title != nil {
+			pr.Title = edit.Title
+		}
+		if edit.Body != nil {
+			pr.Body = edit.Body
+		}
+		if edit.State != nil {
+			pr.State = edit.State
+		}
+		f.json(w, f.fill(pr))
+	} else if c := m("GET", "issues/*"); c != nil {
+		pr := f.pull(w, num(c[0]))
+		f.json(w, &github.Issue{
+			Number: pr.Number, Labels: pr.Labels, Body: pr.Body,
+			Reactions: &github.Reactions{PlusOne: github.Int(f.reactions[pr.GetNumber()])},
+		})
+	} else if c := m("GET", "issues/*/labels"); c != nil {
+		f.json(w, f.pull(w, num(c[0])).Labels)
+	} else if c := m("POST", "issues/*/labels"); c != nil {
+		pr := f.pull(w, num(c[0]))
+		var names []string
+		f.decode(r, &names)
+		for _, n := range names {
+			if matchedLabel(pr.Labels, []string{n}) == "" {
+				pr.Labels = append(pr.Labels, &github.Label{Name: github.String(n)})
+			}
+		}
+		f.json(w, pr.Labels)
+	} else if c := m("GET", "issues/*/comments"); c != nil {
+		coms := f.comments[num(c[0])]
+		page := f.page(r, len(coms), w)
+		f.json(w, coms[page[0]:page[1]])
+	} else if c := m("POST", "issues/*/comments"); c != nil {
+		var com github.IssueComment
+		f.decode(r, &com)
+		f.nextID++
+		com.ID = github.Int64(f.nextID)
+		f.comments[num(c[0])] = append(f.comments[num(c[0])], &com)
+		f.json(w, &com)
+	} else if c := m("PATCH", "issues/comments/*"); c != nil {
+		var edit github.IssueComment
+		f.decode(r, &edit)
+		for _, coms := range f.comments {
+			for _, com := range coms {
+				if strconv.FormatInt(com.GetID(), 10) == c[0] {
+					com.Body = edit.Body
+					f.json(w, com)
+					return
+				}
+			}
+		}
+		f.notFound(w)
+	} else if c := m("POST", "releases/generate-notes"); c != nil {
+		var opt github.GenerateNotesOptions
+		f.decode(r, &opt)
+		render := f.notes
+		if render == nil {
+			render = func(tag, prev string) string {
+				return fmt.Sprintf("## What's Changed\n* Add feature by @Songmu in https://github.com/%s/%s/pull/100\n\n"+
+					"**Full Changelog**: https://github.com/%s/%s/compare/%s...%s", testOwner, testRepo, testOwner, testRepo, prev, tag)
+			}
+		}
+		f.json(w, &github.RepositoryReleaseNotes{Name: opt.TagName, Body: render(opt.TagName, opt.GetPreviousTagName())})
+	} else if c := m("GET", "releases/tags/**"); c != nil {
+		for _, rel := range f.releases {
+			if rel.GetTagName() == c[0] {
+				f.json(w, rel)
+				return
+			}
+		}
+		f.notFound(w)
+	} else if c := m("POST", "releases"); c != nil {
+		var rel github.RepositoryRelease
+		f.decode(r, &rel)
+		f.nextID++
+		rel.ID = github.Int64(f.nextID)
+		f.releases = append(f.releases, &rel)
+		f.json(w, &rel)
+	} else if c := m("PATCH", "releases/*"); c != nil {
+		rel := f.release(c[0])
+		if rel == nil {
+			f.notFound(w)
+			return
+		}
+		var edit github.RepositoryRelease
+		f.decode(r, &edit)
+		if edit.Body != nil {
+			rel.Body = edit.Body
+		}
+		f.json(w, rel)
+	} else if c := m("GET", "releases/*/assets"); c != nil {
+		id, _ := strconv.ParseInt(c[0], 10, 64)
+		f.json(w, f.assets[id])
+	} else if c := m("POST", "releases/*/assets"); c != nil {
+		if f.failUpload {
+			w.WriteHeader(http.StatusBadGateway)
+			f.json(w, map[string]string{"message": "Bad Gateway"})
+			return
+		}
+		id, _ := strconv.ParseInt(c[0], 10, 64)
+		f.nextID++
+		a := &github.ReleaseAsset{ID: github.Int64(f.nextID), Name: github.String(r.URL.Query().Get("name"))}
+		f.assets[id] = append(f.assets[id], a)
+		w.WriteHeader(http.StatusCreated)
+		f.json(w, a)
+	} else if c := m("DELETE", "git/refs/**"); c != nil {
+		cmd := exec.Command("git", "--git-dir", f.remote, "update-ref", "-d", "refs/"+c[0])
+		if err := cmd.Run(); err != nil {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			f.json(w, map[string]string{"message": "Reference does not exist"})
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	} else if c := m("GET", "branches/*/protection/required_status_checks"); c != nil {
+		if f.requiredChecks == nil {
+			f.notFound(w)
+			return
+		}
+		f.json(w, &github.RequiredStatusChecks{Contexts: f.requiredChecks})
+	} else if c := m("GET", "commits/*/check-runs"); c != nil {
+		page := f.page(r, len(f.checkRuns), w)
+		f.json(w, &github.ListCheckRunsResults{Total: github.Int(len(f.checkRuns)), CheckRuns: f.checkRuns[page[0]:page[1]]})
+	} else if c := m("GET", "commits/*/status"); c != nil {
+		page := f.page(r, len(f.statuses), w)
+		f.json(w, &github.CombinedStatus{TotalCount: github.Int(len(f.statuses)), Statuses: f.statuses[page[0]:page[1]]})
+	} else {
+		f.t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		f.notFound(w)
+	}
+}
+
+// page returns the range of the items of the requested page, and sets the Link header
+// for the next page as GitHub does.
+func (f *fakeGitHub) page(r *http.Request, total int, w http.ResponseWriter) [2]int {
+	p, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if p < 1 {
+		p = 1
+	}
+	per, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+	if per < 1 {
+		per = 30
+	}
+	start, end := (p-1)*per, p*per
+	if start > total {
+		start = total
+	}
+	if end >= total {
+		end = total
+	} else {
+		next := *r.URL
+		q := next.Query()
+		q.Set("page", strconv.Itoa(p+1))
+		next.RawQuery = q.Encode()
+		w.Header().Set("Link", fmt.Sprintf(`<%s%s>; rel="next"`, f.srv.URL, next.RequestURI()))
+	}
+	return [2]int{start, end}
+}
+
+// fill returns the copy of the pull request with the current head SHA in the remote
+func (f *fakeGitHub) fill(pr *github.PullRequest) *github.PullRequest {
+	cp := *pr
+	head := *pr.Head
+	if out, err := exec.Command("git", "--git-dir", f.remote,
+		"rev-parse", "refs/heads/"+head.GetRef()).Output(); err == nil {
+		head.SHA = github.String(strings.TrimSpace(string(out)))
+	}
+	cp.Head = &head
+	return &cp
+}
+
+func (f *fakeGitHub) pull(w http.ResponseWriter, num int) *github.PullRequest {
+	if num < 1 || num > len(f.pulls) {
+		f.t.Fatalf("no pull request #%d", num)
+	}
+	return f.pulls[num-1]
+}
+
+func (f *fakeGitHub) release(id string) *github.RepositoryRelease {
+	for _, rel := range f.releases {
+		if strconv.FormatInt(rel.GetID(), 10) == id {
+			return rel
+		}
+	}
+	return nil
+}
+
+func (f *fakeGitHub) decode(r *http.Request, v interface{}) {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		f.t.Errorf("failed to decode the request of %s %s: %s", r.Method, r.URL.Path, err)
+	}
+}
+
+func (f *fakeGitHub) json(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func (f *fakeGitHub) notFound(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusNotFound)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Not Found"})
+}
+
+// openPulls returns the open pull requests
+func (f *fakeGitHub) openPulls() []*github.PullRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var pulls []*github.PullRequest
+	for _, pr := range f.pulls {
+		if pr.GetState() == "open" {
+			pulls = append(pulls, f.fill(pr))
+		}
+	}
+	return pulls
+}
+
+func (f *fakeGitHub) addLabel(num int, name string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	pr := f.pulls[num-1]
+	pr.Labels = append(pr.Labels, &github.Label{Name: github.String(name)})
+}
@@ -120,6 +120,12 @@ func versionFile(files []string) (string, string) {
 			return f, "node"
 		case "pom.xml":
 			return f, "java"
+		case "gradle.properties", "build.gradle", "build.gradle.kts":
+			return f, "gradle"
+		case "chart.yaml":
+			return f, "helm"
+		case "dockerfile":
+			return f, "docker"
 		case "meta.json":
 			if meta == "" {
 				meta = f
@@ -164,37 +170,207 @@ func (fl *fileList) list() []string {
 	return fl.l
 }
 
-func bumpVersionFile(fpath string, from, to *semv) error {
+// bumpVersionFile replaces the first occurrence of the version in the file and reports
+// whether it is replaced. If the spec has the locator like "config.json#/metadata/version",
+// only the value at the locator is replaced.
+func bumpVersionFile(spec string, from, to *semv) (bool, error) {
+	txn := newFileTxn(false)
+	replaced, err := txn.bumpVersionFile(spec, from, to)
+	if err != nil {
+		return false, err
+	}
+	return replaced, txn.commit()
+}
+
+// bumpedVersionFile returns the path and the content of the version file bumped as
+// bumpVersionFile without writing it. The content is nil if the version is not found.
+// In the Gradle files, the occurrence following the "version" keyword is preferred,
+// e.g. `version = "1.2.3"` over `implementation 'foo:bar:1.2.3'` in build.gradle.
+// If normalize is true, the version in the non-standard form like "01.02.03" is also replaced.
+func bumpedVersionFile(read func(string) ([]byte, error), spec string, from, to *semv, normalize bool) (string, []byte, error) {
+	fpath, locator := splitVersionFile(spec)
+	vPrefix, err := versionFileVPrefix(spec)
+	if err != nil {
+		return "", nil, err
+	}
 	verReg, err := regexp.Compile(`(v|\b)` + regexp.QuoteMeta(from.Naked()) + `\b`)
 	if err != nil {
-		return err
+		return "", nil, err
 	}
-	bs, err := os.ReadFile(fpath)
+	bs, err := read(fpath)
 	if err != nil {
-		return err
+		return "", nil, err
+	}
+	if locator != "" {
+		start, end, err := locateVersion(bs, fpath, locator)
+		if err != nil {
+			return "", nil, err
+		}
+		ver := strings.TrimPrefix(string(bs[start:end]), "v")
+		if normalize {
+			ver, _ = normalizeVersion(ver)
+		}
+		if ver != from.Naked() {
+			return fpath, nil, nil
+		}
+		if bs[start] == 'v' {
+			start++
+		}
+		return fpath, replaceVersion(bs, start, end, to, vPrefix), nil
+	}
+	var kwBase string
+	switch {
+	case isDockerfile(fpath):
+		kwBase = ociVersionLabelRegBase
+	case isGradleFile(fpath):
+		kwBase = versionRegBase
+	}
+	if kwBase != "" {
+		kwReg, err := regexp.Compile(kwBase + `v?(` + regexp.QuoteMeta(from.Naked()) + `)\b`)
+		if err != nil {
+			return "", nil, err
+		}
+		if loc := kwReg.FindSubmatchIndex(bs); loc != nil {
+			return fpath, replaceVersion(bs, loc[4], loc[5], to, vPrefix), nil
+		}
+	}
+	if loc := verReg.FindSubmatchIndex(bs); loc != nil {
+		return fpath, replaceVersion(bs, loc[3], loc[1], to, vPrefix), nil
+	}
+	if normalize {
+		return fpath, replaceLooseVersion(bs, from, to, vPrefix), nil
+	}
+	return fpath, nil, nil
+}
+
+// replaceVersion replaces bs[start:end], the version without the v-prefix, with the to version.
+// If vPrefix is specified for the version file, the preceding "v" is added or removed by it.
+// Otherwise the v-prefix in the file is kept as is.
+func replaceVersion(bs []byte, start, end int, to *semv, vPrefix *bool) []byte {
+	ver := to.Naked()
+	if vPrefix != nil {
+		hasV := start > 0 && bs[start-1] == 'v'
+		if *vPrefix && !hasV {
+			ver = "v" + ver
+		} else if !*vPrefix && hasV {
+			start--
+		}
+	}
+	return append(append(append([]byte{}, bs[:start]...), ver...), bs[end:]...)
+}
+
+// the version label of the OCI image spec in the Dockerfile, e.g.
+// LABEL org.opencontainers.image.version="1.2.3" org.opencontainers.image.title="app"
+const ociVersionLabelRegBase = `(org\.opencontainers\.image\.version=["']?)`
+
+var ociVersionLabelReg = regexp.MustCompile(ociVersionLabelRegBase + `v?([0-9]+\.[0-9]+\.[0-9]+)`)
+
+func isDockerfile(fpath string) bool {
+	base := strings.ToLower(filepath.Base(fpath))
+	return base == "dockerfile" || strings.HasPrefix(base, "dockerfile.") || strings.HasSuffix(base, ".dockerfile")
+}
+
+// isXMLFile reports whether the file is the XML like pom.xml of Maven, whose version may have
+// the "-SNAPSHOT" suffix.
+func isXMLFile(fpath string) bool {
+	return strings.EqualFold(filepath.Ext(fpath), ".xml")
+}
+
+func isGradleFile(fpath string) bool {
+	switch strings.ToLower(filepath.Base(fpath)) {
+	case "gradle.properties", "build.gradle", "build.gradle.kts":
+		return true
+	}
+	return false
+}
+
+func isChartYAML(fpath string) bool {
+	return filepath.Base(fpath) == "Chart.yaml"
+}
+
+// bumpChartYAML sets the values of the top-level keys in the Chart.yaml of Helm to the version
+// line by line to preserve the formatting and the comments, and reports whether any of them
+// are replaced. e.g. "version" and "appVersion"
+func bumpChartYAML(fpath string, keys []string, to *semv) (bool, error) {
+	txn := newFileTxn(false)
+	replaced, err := txn.bumpChartYAML(fpath, keys, to)
+	if err != nil {
+		return false, err
 	}
+	return replaced, txn.commit()
+}
 
+// bumpedChartYAML returns the content of the Chart.yaml bumped as bumpChartYAML without
+// writing it. The content is nil if none of the keys are found.
+func bumpedChartYAML(read func(string) ([]byte, error), fpath string, keys []string, to *semv) ([]byte, error) {
+	bs, err := read(fpath)
+	if err != nil {
+		return nil, err
+	}
 	replaced := false
-	updated := verReg.ReplaceAllFunc(bs, func(match []byte) []byte {
-		if replaced {
-			return match
+	for _, key := range keys {
+		reg, err := regexp.Compile(`(?m)^(` + regexp.QuoteMeta(key) +
+			`:[ \t]*["']?v?)[0-9]+\.[0-9]+\.[0-9]+(?:[-+][-+.0-9A-Za-z]*)?`)
+		if err != nil {
+			return nil, err
 		}
-		replaced = true
-		return verReg.ReplaceAll(match, []byte(`${1}`+to.Naked()))
-	})
-	return os.WriteFile(fpath, updated, 0666)
+		if reg.Match(bs) {
+			bs = reg.ReplaceAll(bs, []byte(`${1}`+to.Naked()))
+			replaced = true
+		}
+	}
+	if !replaced {
+		return nil, nil
+	}
+	return bs, nil
 }
 
-func retrieveVersionFromFile(fpath string, vPrefix bool) (*semv, error) {
+func retrieveVersionFromFile(spec string, vPrefix, normalize bool) (*semv, error) {
+	fpath, locator := splitVersionFile(spec)
 	bs, err := os.ReadFile(fpath)
 	if err != nil {
 		return nil, err
 	}
-	m := versionReg.FindSubmatch(bs)
+	if locator != "" {
+		start, end, err := locateVersion(bs, fpath, locator)
+		if err != nil {
+			return nil, err
+		}
+		ver := strings.TrimPrefix(string(bs[start:end]), "v")
+		if normalize {
+			if ver, err = normalizeVersion(ver); err != nil {
+				return nil, fmt.Errorf("%w: %s: %s", ErrInvalidVersionFile, spec, err)
+			}
+		}
+		if vPrefix {
+			ver = "v" + ver
+		}
+		sv, err := newSemver(ver)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %s", ErrInvalidVersionFile, spec, err)
+		}
+		return sv, nil
+	}
+	var m [][]byte
+	if isDockerfile(fpath) {
+		m = ociVersionLabelReg.FindSubmatch(bs)
+	}
+	if len(m) < 3 {
+		if normalize {
+			m = looseVersionReg.FindSubmatch(bs)
+		} else {
+			m = versionReg.FindSubmatch(bs)
+		}
+	}
 	if len(m) < 3 {
-		return nil, fmt.Errorf("no version detected from file: %s", fpath)
+		return nil, fmt.Errorf("%w: no version detected from file: %s", ErrInvalidVersionFile, fpath)
 	}
 	ver := string(m[2])
+	if normalize {
+		if ver, err = normalizeVersion(ver); err != nil {
+			return nil, fmt.Errorf("%w: %s: %s", ErrInvalidVersionFile, fpath, err)
+		}
+	}
 	if vPrefix {
 		ver = "v" + ver
 	}
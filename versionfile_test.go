@@ -1,6 +1,8 @@
 package tagpr
 
 import (
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 )
@@ -28,7 +30,7 @@ func TestFileOrder(t *testing.T) {
 	}
 }
 func TestRetrieveVersionFile(t *testing.T) {
-	ver, err := retrieveVersionFromFile("version.go", false)
+	ver, err := retrieveVersionFromFile("version.go", false, false)
 	if err != nil {
 		t.Error(err)
 	}
@@ -58,3 +60,158 @@ func TestDetectVersionFile_perl(t *testing.T) {
 		t.Errorf("error: %s", f)
 	}
 }
+
+func TestDetectVersionFile_gradle(t *testing.T) {
+	testCases := []struct {
+		root, expect string
+	}{
+		{"testdata/gradle", "gradle.properties"},
+		{"testdata/gradle-kts", "build.gradle.kts"},
+	}
+	v, _ := newSemver("v1.4.2")
+	for _, tc := range testCases {
+		t.Run(tc.root, func(t *testing.T) {
+			f, err := detectVersionFile(tc.root, v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if f != tc.expect {
+				t.Errorf("got: %s, expected: %s", f, tc.expect)
+			}
+		})
+	}
+}
+
+func TestBumpVersionFile_gradle(t *testing.T) {
+	testCases := []struct {
+		name, content, expect string
+	}{{
+		name:    "gradle.properties",
+		content: "org.gradle.jvmargs=-Xmx2g\nversion=1.4.2\n",
+		expect:  "org.gradle.jvmargs=-Xmx2g\nversion=1.5.0\n",
+	}, {
+		name:    "build.gradle",
+		content: "dependencies {\n    implementation 'com.example:sibling:1.4.2'\n}\nversion = '1.4.2'\n",
+		expect:  "dependencies {\n    implementation 'com.example:sibling:1.4.2'\n}\nversion = '1.5.0'\n",
+	}, {
+		name:    "build.gradle.kts",
+		content: "implementation(\"com.example:sibling:1.4.2\")\nversion = \"1.4.2\" // release\n",
+		expect:  "implementation(\"com.example:sibling:1.4.2\")\nversion = \"1.5.0\" // release\n",
+	}, {
+		// the keyword isn't preferred other than in the Gradle files
+		name:    "version.go",
+		content: "package app\n\n// 1.4.2\nconst version = \"1.4.2\"\n",
+		expect:  "package app\n\n// 1.5.0\nconst version = \"1.4.2\"\n",
+	}}
+	from, _ := newSemver("1.4.2")
+	to, _ := newSemver("1.5.0")
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fpath := filepath.Join(t.TempDir(), tc.name)
+			if err := os.WriteFile(fpath, []byte(tc.content), 0666); err != nil {
+				t.Fatal(err)
+			}
+			replaced, err := bumpVersionFile(fpath, from, to)
+			if err != nil {
+				t.Fatal(err)
+			}
+			bs, _ := os.ReadFile(fpath)
+			if !replaced || string(bs) != tc.expect {
+				t.Errorf("got:\n%s\nexpected:\n%s", bs, tc.expect)
+			}
+		})
+	}
+}
+
+func TestBumpChartYAML(t *testing.T) {
+	const content = `apiVersion: v2
+name: app
+# the chart version
+version: 0.3.0 # keep in sync
+appVersion: "1.2.3"
+dependencies:
+  - name: common
+    version: 0.3.0
+`
+	testCases := []struct {
+		name   string
+		keys   []string
+		expect string
+	}{{
+		name: "version only",
+		keys: []string{"version"},
+		expect: `apiVersion: v2
+name: app
+# the chart version
+version: 0.4.0 # keep in sync
+appVersion: "1.2.3"
+dependencies:
+  - name: common
+    version: 0.3.0
+`,
+	}, {
+		name: "with appVersion",
+		keys: []string{"version", "appVersion"},
+		expect: `apiVersion: v2
+name: app
+# the chart version
+version: 0.4.0 # keep in sync
+appVersion: "0.4.0"
+dependencies:
+  - name: common
+    version: 0.3.0
+`,
+	}}
+	to, _ := newSemver("0.4.0")
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fpath := filepath.Join(t.TempDir(), "Chart.yaml")
+			if err := os.WriteFile(fpath, []byte(content), 0666); err != nil {
+				t.Fatal(err)
+			}
+			replaced, err := bumpChartYAML(fpath, tc.keys, to)
+			if err != nil {
+				t.Fatal(err)
+			}
+			bs, _ := os.ReadFile(fpath)
+			if !replaced || string(bs) != tc.expect {
+				t.Errorf("got:\n%s\nexpected:\n%s", bs, tc.expect)
+			}
+		})
+	}
+}
+
+func TestDockerfileVersionLabel(t *testing.T) {
+	const content = `FROM golang:1.19.2 AS builder
+LABEL version="0.9.0"
+LABEL org.opencontainers.image.title="app" \
+      org.opencontainers.image.version="1.2.3" \
+      org.opencontainers.image.vendor="Songmu"
+`
+	fpath := filepath.Join(t.TempDir(), "Dockerfile")
+	if err := os.WriteFile(fpath, []byte(content), 0666); err != nil {
+		t.Fatal(err)
+	}
+	v, err := retrieveVersionFromFile(fpath, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Naked() != "1.2.3" {
+		t.Errorf("got: %s, expected: 1.2.3", v.Naked())
+	}
+	to, _ := newSemver("1.3.0")
+	replaced, err := bumpVersionFile(fpath, v, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := `FROM golang:1.19.2 AS builder
+LABEL version="0.9.0"
+LABEL org.opencontainers.image.title="app" \
+      org.opencontainers.image.version="1.3.0" \
+      org.opencontainers.image.vendor="Songmu"
+`
+	bs, _ := os.ReadFile(fpath)
+	if !replaced || string(bs) != expect {
+		t.Errorf("got:\n%s\nexpected:\n%s", bs, expect)
+	}
+}